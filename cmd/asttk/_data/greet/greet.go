@@ -0,0 +1,15 @@
+// Package greet print greetings.
+package greet
+
+import (
+	"fmt"
+)
+
+// Greet print a greeting for the given name.
+func Greet(name string) {
+	fmt.Println(hello(name)) // print
+}
+
+func hello(name string) string {
+	return "Hello " + name
+}
@@ -0,0 +1,11 @@
+package imports
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+func upper(s string) string {
+	return fmt.Sprint(strings.ToUpper(s))
+}
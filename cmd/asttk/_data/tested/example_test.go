@@ -0,0 +1,12 @@
+package tested_test
+
+import (
+	"fmt"
+
+	"github.com/negrel/asttk/cmd/asttk/_data/tested"
+)
+
+func ExampleGreet() {
+	fmt.Println(tested.Greet("World"))
+	// Output: Hello World
+}
@@ -0,0 +1,6 @@
+package tested
+
+// Greet return a greeting for the given name.
+func Greet(name string) string {
+	return "Hello " + name
+}
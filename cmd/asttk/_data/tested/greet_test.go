@@ -0,0 +1,9 @@
+package tested
+
+import "testing"
+
+func TestGreet(t *testing.T) {
+	if Greet("World") != "Hello World" {
+		t.Fail()
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/types"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/negrel/asttk/pkg/inspector"
+	"github.com/negrel/asttk/pkg/utils"
+)
+
+var stripComments = &command{
+	name: "strip-comments",
+	usage: `Remove the comments of the given files.
+The -mode flag select the removed comments: all, doc, non-doc or non-directive.
+Directives are kept by every mode except all.`,
+	syntaxOnly: true,
+	setup: func(flags *flag.FlagSet) func(*target, io.Writer) error {
+		mode := flags.String("mode", "all", "comments to remove: all, doc, non-doc or non-directive")
+
+		return func(t *target, _ io.Writer) error {
+			var remover inspector.Inspector
+			switch *mode {
+			case "all":
+				remover = utils.RemoveAllComments()
+			case "doc":
+				remover = utils.RemoveDocComments()
+			case "non-doc":
+				remover = utils.RemoveNonDocComments()
+			case "non-directive":
+				remover = utils.RemoveNonDirectiveComments()
+			default:
+				return errUsage
+			}
+
+			for _, file := range t.files {
+				inspector.New(remover).Inspect(file.AST())
+			}
+
+			return nil
+		}
+	},
+}
+
+var rmUnusedImports = &command{
+	name:  "rm-unused-imports",
+	usage: "Remove the unused imports of the given files.",
+	setup: func(_ *flag.FlagSet) func(*target, io.Writer) error {
+		return func(t *target, _ io.Writer) error {
+			for _, file := range t.files {
+				info := file.Package().TypesInfo()
+				if info == nil {
+					return fmt.Errorf("%v: no type information", file.Path())
+				}
+
+				collector, remover := utils.RemoveUnusedImports(file.FileSet(), info)
+				inspector.New(collector).Inspect(file.AST())
+				remover(file.AST())
+			}
+
+			return nil
+		}
+	},
+}
+
+var renameFunc = &command{
+	name: "rename-func",
+	usage: `Rename a function or a method and every reference to it.
+Methods are designated by their receiver type name: -from Type.Method.
+It fails if no function is named -from.`,
+	packageWide: true,
+	setup: func(flags *flag.FlagSet) func(*target, io.Writer) error {
+		from := flags.String("from", "", "name of the renamed function, Type.Method for methods")
+		to := flags.String("to", "", "new name of the function")
+
+		return func(t *target, _ io.Writer) error {
+			if *from == "" || *to == "" {
+				return errUsage
+			}
+
+			found := false
+			err := utils.RenameFuncs(t.pkg, func(fn *types.Func) (string, bool) {
+				if funcName(fn) != *from {
+					return "", false
+				}
+
+				found = true
+				return *to, true
+			})
+			if err == nil && !found {
+				err = fmt.Errorf("no function named %v", *from)
+			}
+
+			return err
+		}
+	},
+}
+
+var changePackage = &command{
+	name: "change-package",
+	usage: `Change the package name of the given package.
+The external test package is renamed accordingly and the references to the
+package are updated in it and, with -r, in the sub-packages.`,
+	packageWide: true,
+	setup: func(flags *flag.FlagSet) func(*target, io.Writer) error {
+		name := flags.String("name", "", "new package name")
+
+		return func(t *target, _ io.Writer) error {
+			if *name == "" {
+				return errUsage
+			}
+
+			return utils.RenamePackage(t.pkg, utils.PackageRename{
+				Name:      *name,
+				Importers: t.pkg.SubPkgs(),
+			})
+		}
+	},
+}
+
+var inspect = &command{
+	name: "inspect",
+	usage: `Print the position and the first line of the nodes of a given type.
+The -type flag select the node type, FuncDecl by default.`,
+	readOnly:   true,
+	syntaxOnly: true,
+	setup: func(flags *flag.FlagSet) func(*target, io.Writer) error {
+		nodeType := flags.String("type", "FuncDecl", "type of the printed nodes, CallExpr or *ast.CallExpr for example")
+
+		return func(t *target, stdout io.Writer) error {
+			name := strings.TrimPrefix(strings.TrimPrefix(*nodeType, "*"), "ast.")
+
+			for _, file := range t.files {
+				inspector.New(func(node ast.Node) bool {
+					if node == nil || reflect.TypeOf(node).Elem().Name() != name {
+						return true
+					}
+
+					src, err := file.Source(node)
+					if err != nil {
+						return true
+					}
+					if i := bytes.IndexByte(src, '\n'); i >= 0 {
+						src = src[:i]
+					}
+
+					fmt.Fprintf(stdout, "%v: %s\n", file.Position(node), src)
+
+					return true
+				}).Inspect(file.AST())
+			}
+
+			return nil
+		}
+	},
+}
+
+// funcName return the name of the given function, prefixed with the name of
+// its receiver type if it is a method.
+func funcName(fn *types.Func) string {
+	sig, ok := fn.Type().(*types.Signature)
+	if !ok || sig.Recv() == nil {
+		return fn.Name()
+	}
+
+	recv := sig.Recv().Type()
+	if ptr, isPtr := recv.(*types.Pointer); isPtr {
+		recv = ptr.Elem()
+	}
+	if named, isNamed := recv.(*types.Named); isNamed {
+		return named.Obj().Name() + "." + fn.Name()
+	}
+
+	return fn.Name()
+}
@@ -0,0 +1,234 @@
+// Command asttk apply the editors of the toolkit to go files and packages.
+//
+// Usage:
+//
+//	asttk <command> [flags] path...
+//
+// The paths are go files or package directories. By default, the edited files
+// are printed to the standard output, like gofmt:
+//
+//	-r  process the sub-packages of the given packages
+//	-t  process the test files and the external test packages
+//	-w  write the result to the source files instead of stdout
+//	-d  display a diff instead of the rewritten files
+//	-l  list the files whose content differs from the result
+//
+// The commands are strip-comments, rm-unused-imports, rename-func,
+// change-package and inspect. Run "asttk <command> -h" for the command flags.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/negrel/asttk/pkg/parse"
+)
+
+// errUsage is returned by the commands invoked with invalid flags.
+var errUsage = errors.New("invalid usage")
+
+type options struct {
+	recursive bool
+	tests     bool
+	write     bool
+	diff      bool
+	list      bool
+}
+
+// target is a file or package given on the command line.
+type target struct {
+	pkg   *parse.GoPackage
+	files []*parse.GoFile
+}
+
+type command struct {
+	name  string
+	usage string
+	// readOnly commands don't edit the files, they only accept the -r and -t flags.
+	readOnly bool
+	// packageWide commands edit every file of a package, a file given on
+	// the command line is processed with the rest of its package.
+	packageWide bool
+	// syntaxOnly commands don't need type information, the files are parsed
+	// with go/parser instead of being loaded by the go command.
+	syntaxOnly bool
+	// setup register the command flags and return the function applying the
+	// command to a target.
+	setup func(flags *flag.FlagSet) func(t *target, stdout io.Writer) error
+}
+
+var commands = []*command{
+	stripComments,
+	rmUnusedImports,
+	renameFunc,
+	changePackage,
+	inspect,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run execute the command line and return the exit code: 0 on success, 1 if
+// a path couldn't be processed and 2 on usage errors.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+
+	var cmd *command
+	for _, c := range commands {
+		if c.name == args[0] {
+			cmd = c
+		}
+	}
+	if cmd == nil {
+		fmt.Fprintf(stderr, "asttk: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	flags := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: asttk %v [flags] path...\n\n%v\n\nflags:\n", cmd.name, cmd.usage)
+		flags.PrintDefaults()
+	}
+
+	opts := options{}
+	flags.BoolVar(&opts.recursive, "r", false, "process the sub-packages of the given packages")
+	flags.BoolVar(&opts.tests, "t", false, "process the test files and the external test packages")
+	if !cmd.readOnly {
+		flags.BoolVar(&opts.write, "w", false, "write the result to the source files instead of stdout")
+		flags.BoolVar(&opts.diff, "d", false, "display a diff instead of the rewritten files")
+		flags.BoolVar(&opts.list, "l", false, "list the files whose content differs from the result")
+	}
+	apply := cmd.setup(flags)
+
+	if err := flags.Parse(args[1:]); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	exitCode := 0
+	for _, path := range flags.Args() {
+		err := process(cmd, apply, path, opts, stdout)
+		if err == errUsage {
+			flags.Usage()
+			return 2
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "asttk: %v: %v\n", path, err)
+			exitCode = 1
+		}
+	}
+
+	return exitCode
+}
+
+func usage(output io.Writer) {
+	fmt.Fprintln(output, "usage: asttk <command> [flags] path...")
+	fmt.Fprintln(output, "\ncommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(output, "  %-18v %v\n", cmd.name, strings.SplitN(cmd.usage, "\n", 2)[0])
+	}
+}
+
+func process(cmd *command, apply func(*target, io.Writer) error, path string, opts options, stdout io.Writer) error {
+	t, err := loadTarget(path, opts, cmd)
+	if err != nil {
+		return err
+	}
+
+	err = apply(t, stdout)
+	if err != nil || cmd.readOnly {
+		return err
+	}
+
+	for _, file := range t.files {
+		result, err := file.Write(file.Path(), parse.WriteOptions{DryRun: !opts.write})
+		if err != nil {
+			return err
+		}
+
+		if opts.list && result.Changed {
+			fmt.Fprintln(stdout, file.Path())
+		}
+		if opts.diff {
+			diff, err := file.Diff()
+			if err != nil {
+				return err
+			}
+			_, _ = stdout.Write(diff)
+		}
+		if !opts.write && !opts.diff && !opts.list {
+			_, _ = stdout.Write(result.Content)
+		}
+	}
+
+	return nil
+}
+
+func loadTarget(path string, opts options, cmd *command) (*target, error) {
+	var loadOpts []parse.Option
+	if opts.tests {
+		loadOpts = append(loadOpts, parse.WithTests(), parse.WithXTest())
+	}
+
+	loadFile, loadPkg := parse.File, parse.Package
+	if cmd.syntaxOnly {
+		loadFile, loadPkg = parse.ParseFile, parse.ParseDir
+	}
+
+	if strings.HasSuffix(path, ".go") {
+		file, err := loadFile(path, loadOpts...)
+		if err != nil {
+			return nil, err
+		}
+
+		t := &target{
+			pkg:   file.Package(),
+			files: []*parse.GoFile{file},
+		}
+		if cmd.packageWide {
+			t.files = packageFiles(t.pkg)
+		}
+
+		return t, nil
+	}
+
+	pkg, err := loadPkg(path, opts.recursive, loadOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &target{
+		pkg:   pkg,
+		files: packageFiles(pkg),
+	}, nil
+}
+
+// packageFiles return the files of the given package, its external test package
+// and its sub-packages.
+func packageFiles(pkg *parse.GoPackage) []*parse.GoFile {
+	files := pkg.AllFiles()
+	if xtest := pkg.XTest(); xtest != nil {
+		files = append(files, xtest.Files...)
+	}
+	for _, subPkg := range pkg.SubPkgs() {
+		files = append(files, packageFiles(subPkg)...)
+	}
+
+	return files
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func runCmd(args ...string) (exitCode int, stdout, stderr string) {
+	out, errOut := &bytes.Buffer{}, &bytes.Buffer{}
+	exitCode = run(args, out, errOut)
+
+	return exitCode, out.String(), errOut.String()
+}
+
+func TestRun_Usage(t *testing.T) {
+	exitCode, _, stderr := runCmd()
+	assert.Equal(t, 2, exitCode)
+	assert.Contains(t, stderr, "strip-comments")
+
+	exitCode, _, stderr = runCmd("unknown")
+	assert.Equal(t, 2, exitCode)
+	assert.Contains(t, stderr, `unknown command "unknown"`)
+
+	exitCode, _, _ = runCmd("rename-func", "-from", "hello", filepath.Join("_data", "greet"))
+	assert.Equal(t, 2, exitCode)
+
+	exitCode, _, stderr = runCmd("strip-comments", filepath.Join("_data", "missing"))
+	assert.Equal(t, 1, exitCode)
+	assert.NotEmpty(t, stderr)
+}
+
+func TestRun_StripComments(t *testing.T) {
+	exitCode, stdout, stderr := runCmd("strip-comments", filepath.Join("_data", "greet", "greet.go"))
+	assert.Equal(t, 0, exitCode, stderr)
+	assert.Equal(t, `package greet
+
+import (
+	"fmt"
+)
+
+func Greet(name string) {
+	fmt.Println(hello(name))
+}
+
+func hello(name string) string {
+	return "Hello " + name
+}
+`, stdout)
+
+	exitCode, stdout, stderr = runCmd("strip-comments", "-mode", "non-doc", "-d", filepath.Join("_data", "greet"))
+	assert.Equal(t, 0, exitCode, stderr)
+	assert.Contains(t, stdout, "-\tfmt.Println(hello(name)) // print\n+\tfmt.Println(hello(name))\n")
+	assert.NotContains(t, stdout, "-// Greet")
+
+	// Files outside a module are parsed without the go command.
+	dir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\n// main doc.\nfunc main() {}\n"), 0644)
+	assert.Nil(t, err, err)
+
+	exitCode, stdout, stderr = runCmd("strip-comments", dir)
+	assert.Equal(t, 0, exitCode, stderr)
+	assert.Equal(t, "package main\n\nfunc main() {}\n", stdout)
+}
+
+func TestRun_RmUnusedImports(t *testing.T) {
+	path, _ := filepath.Abs(filepath.Join("_data", "imports", "imports.go"))
+
+	exitCode, stdout, stderr := runCmd("rm-unused-imports", "-l", path)
+	assert.Equal(t, 0, exitCode, stderr)
+	assert.Equal(t, path+"\n", stdout)
+
+	exitCode, stdout, stderr = runCmd("rm-unused-imports", "-d", path)
+	assert.Equal(t, 0, exitCode, stderr)
+	assert.Contains(t, stdout, "-\t\"os\"\n")
+}
+
+func TestRun_RenameFunc(t *testing.T) {
+	exitCode, stdout, stderr := runCmd("rename-func", "-from", "hello", "-to", "hi", "-d", filepath.Join("_data", "greet"))
+	assert.Equal(t, 0, exitCode, stderr)
+	assert.Contains(t, stdout, "+\tfmt.Println(hi(name)) // print\n")
+	assert.Contains(t, stdout, "+func hi(name string) string {\n")
+
+	// Nothing to rename.
+	exitCode, stdout, stderr = runCmd("rename-func", "-from", "bye", "-to", "hi", "-l", filepath.Join("_data", "greet"))
+	assert.Equal(t, 1, exitCode, stderr)
+	assert.Empty(t, stdout)
+	assert.Contains(t, stderr, "no function named bye")
+}
+
+func TestRun_Tests(t *testing.T) {
+	dir := filepath.Join("_data", "tested")
+
+	exitCode, stdout, stderr := runCmd("rename-func", "-from", "Greet", "-to", "Hello", "-l", dir)
+	assert.Equal(t, 0, exitCode, stderr)
+	assert.Equal(t, 1, strings.Count(stdout, "\n"))
+
+	exitCode, stdout, stderr = runCmd("rename-func", "-t", "-from", "Greet", "-to", "Hello", "-l", dir)
+	assert.Equal(t, 0, exitCode, stderr)
+	assert.Contains(t, stdout, "greet.go\n")
+	assert.Contains(t, stdout, "greet_test.go\n")
+	assert.Contains(t, stdout, "example_test.go\n")
+}
+
+func TestRun_ChangePackage(t *testing.T) {
+	exitCode, stdout, stderr := runCmd("change-package", "-name", "salute", "-d", filepath.Join("_data", "greet"))
+	assert.Equal(t, 0, exitCode, stderr)
+	assert.Contains(t, stdout, "-package greet\n+package salute\n")
+
+	// The external test package and its references are renamed.
+	exitCode, stdout, stderr = runCmd("change-package", "-t", "-name", "salute", "-d", filepath.Join("_data", "tested"))
+	assert.Equal(t, 0, exitCode, stderr)
+	assert.Contains(t, stdout, "-package tested\n+package salute\n")
+	assert.Contains(t, stdout, "-package tested_test\n+package salute_test\n")
+	assert.Contains(t, stdout, "-\tfmt.Println(tested.Greet(\"World\"))\n+\tfmt.Println(salute.Greet(\"World\"))\n")
+}
+
+func TestRun_Inspect(t *testing.T) {
+	exitCode, stdout, stderr := runCmd("inspect", "-type", "*ast.CallExpr", filepath.Join("_data", "greet"))
+	assert.Equal(t, 0, exitCode, stderr)
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	assert.Len(t, lines, 2)
+	assert.True(t, strings.HasSuffix(lines[0], "greet.go:10:2: fmt.Println(hello(name))"), lines[0])
+	assert.True(t, strings.HasSuffix(lines[1], "greet.go:10:14: hello(name)"), lines[1])
+}
+
+func TestRun_Write(t *testing.T) {
+	// The package must be part of the module to be loaded.
+	dir, err := ioutil.TempDir("_data", "write")
+	assert.Nil(t, err, err)
+	defer os.RemoveAll(dir)
+
+	src, err := ioutil.ReadFile(filepath.Join("_data", "greet", "greet.go"))
+	assert.Nil(t, err, err)
+	path := filepath.Join(dir, "greet.go")
+	err = ioutil.WriteFile(path, src, 0600)
+	assert.Nil(t, err, err)
+
+	exitCode, stdout, stderr := runCmd("rename-func", "-from", "Greet", "-to", "Hello", "-w", path)
+	assert.Equal(t, 0, exitCode, stderr)
+	assert.Empty(t, stdout)
+
+	content, err := ioutil.ReadFile(path)
+	assert.Nil(t, err, err)
+	assert.Contains(t, string(content), "func Hello(name string) {")
+
+	fileInfo, err := os.Stat(path)
+	assert.Nil(t, err, err)
+	assert.Equal(t, os.FileMode(0600), fileInfo.Mode().Perm())
+}
@@ -1,9 +1,16 @@
 module github.com/negrel/asttk
 
-go 1.15
+go 1.22.0
 
 require (
-	github.com/google/go-cmp v0.5.2
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/stretchr/testify v1.6.1
-	golang.org/x/tools v0.0.0-20201007032633-0806396f153e
+	golang.org/x/mod v0.23.0
+	golang.org/x/tools v0.30.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	golang.org/x/sync v0.11.0 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
 )
@@ -0,0 +1,11 @@
+package a
+
+func greet(name string) {
+	println("Hello", name) // want "use of the println builtin"
+	print(name)
+}
+
+func main() {
+	greet("World")
+	println() // want "use of the println builtin"
+}
@@ -0,0 +1,6 @@
+package b
+
+func main() {
+	println("Hello") // want "replace Ident"
+	panic("unreachable") // want "delete ExprStmt"
+}
@@ -0,0 +1,9 @@
+package c
+
+func f(a int, b string, c ...int) {} // want "delete Field"
+
+func main() {
+	f(1, "2", 3, 2) // want "delete BasicLit"
+	f(1, "3", 2)    // want "delete BasicLit"
+	f(1, "4", 2, 5) // want "delete BasicLit"
+}
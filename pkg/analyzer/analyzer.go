@@ -0,0 +1,132 @@
+// Package analyzer run asttk inspectors as go/analysis analyzers so they can be
+// used by multichecker, go vet -vettool or gopls.
+package analyzer
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	astinspector "golang.org/x/tools/go/ast/inspector"
+
+	"github.com/negrel/asttk/pkg/inspector"
+)
+
+// New return an analysis.Analyzer that run the given ReportInspectors on every
+// file of the analyzed packages and report their diagnostics.
+func New(name, doc string, inspectors ...inspector.ReportInspector) *analysis.Analyzer {
+	return FromLead(name, doc, func(_ *analysis.Pass) *inspector.Lead {
+		return inspector.New().WithReporters(inspectors...)
+	})
+}
+
+// FromLead return an analysis.Analyzer that run the Lead returned by newLead on
+// every file of the analyzed packages and report its diagnostics. newLead is
+// called once per package, the analysis.Pass give access to the type information.
+//
+// The files are found with the result of the inspect analyzer. Each of them is
+// then walked by the Lead with astutil.Apply: the Cursor of a CursorInspector
+// needs the parent field and the index of the current node, which the events of
+// the inspect analyzer don't give.
+//
+// The AST is shared with the other analyzers, the Cursor edits of the Lead are
+// recorded instead of applied, see inspector.Lead.WithRecordedEdits. Every edit
+// is reported as a diagnostic at the edited node with the edit as suggested fix.
+// The severity of the diagnostics isn't reported as go/analysis doesn't support
+// it.
+func FromLead(name, doc string, newLead func(pass *analysis.Pass) *inspector.Lead) *analysis.Analyzer {
+	return &analysis.Analyzer{
+		Name:     name,
+		Doc:      doc,
+		Requires: []*analysis.Analyzer{inspect.Analyzer},
+		Run: func(pass *analysis.Pass) (interface{}, error) {
+			files := pass.ResultOf[inspect.Analyzer].(*astinspector.Inspector)
+			lead := newLead(pass).WithRecordedEdits()
+
+			var err error
+			files.Nodes([]ast.Node{(*ast.File)(nil)}, func(node ast.Node, _ bool) bool {
+				if err == nil {
+					err = applyLead(pass, name, lead, node.(*ast.File))
+				}
+
+				// The children are walked by the Lead.
+				return false
+			})
+
+			return nil, err
+		},
+	}
+}
+
+// applyLead apply the given Lead on the file and report its diagnostics and
+// edits.
+func applyLead(pass *analysis.Pass, category string, lead *inspector.Lead, file *ast.File) error {
+	lead.Apply(file)
+
+	for _, diagnostic := range lead.Diagnostics() {
+		pass.Report(Diagnostic(diagnostic))
+	}
+
+	for _, edit := range lead.Edits() {
+		diagnostic, err := editDiagnostic(pass.Fset, category, edit)
+		if err != nil {
+			return err
+		}
+
+		pass.Report(diagnostic)
+	}
+
+	return nil
+}
+
+// editDiagnostic return a diagnostic suggesting the given edit.
+func editDiagnostic(fset *token.FileSet, category string, edit inspector.Edit) (analysis.Diagnostic, error) {
+	textEdit, err := edit.TextEdit(fset)
+	if err != nil {
+		return analysis.Diagnostic{}, err
+	}
+
+	message := fmt.Sprintf("%v %v", edit.Kind, strings.TrimPrefix(fmt.Sprintf("%T", edit.Node), "*ast."))
+
+	return analysis.Diagnostic{
+		Pos:      edit.Node.Pos(),
+		End:      edit.Node.End(),
+		Category: category,
+		Message:  message,
+		SuggestedFixes: []analysis.SuggestedFix{{
+			Message:   message,
+			TextEdits: []analysis.TextEdit{analysis.TextEdit(textEdit)},
+		}},
+	}, nil
+}
+
+// Diagnostic convert the given inspector.Diagnostic to an analysis.Diagnostic.
+func Diagnostic(diagnostic inspector.Diagnostic) analysis.Diagnostic {
+	fixes := make([]analysis.SuggestedFix, len(diagnostic.SuggestedFixes))
+	for i, fix := range diagnostic.SuggestedFixes {
+		edits := make([]analysis.TextEdit, len(fix.TextEdits))
+		for j, edit := range fix.TextEdits {
+			edits[j] = analysis.TextEdit{
+				Pos:     edit.Pos,
+				End:     edit.End,
+				NewText: edit.NewText,
+			}
+		}
+
+		fixes[i] = analysis.SuggestedFix{
+			Message:   fix.Message,
+			TextEdits: edits,
+		}
+	}
+
+	return analysis.Diagnostic{
+		Pos:            diagnostic.Pos,
+		End:            diagnostic.End,
+		Category:       diagnostic.Category,
+		Message:        diagnostic.Message,
+		SuggestedFixes: fixes,
+	}
+}
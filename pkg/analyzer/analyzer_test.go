@@ -0,0 +1,153 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/types"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/negrel/asttk/pkg/inspector"
+)
+
+func printlnReporter(info *types.Info) inspector.ReportInspector {
+	return func(node ast.Node, pass *inspector.Pass) bool {
+		call, isCall := node.(*ast.CallExpr)
+		if !isCall {
+			return true
+		}
+
+		ident, isIdent := call.Fun.(*ast.Ident)
+		if !isIdent || ident.Name != "println" {
+			return true
+		}
+		if info != nil {
+			if _, isBuiltin := info.Uses[ident].(*types.Builtin); !isBuiltin {
+				return true
+			}
+		}
+
+		pass.Report(inspector.Diagnostic{
+			Pos:      call.Pos(),
+			End:      call.End(),
+			Severity: inspector.SeverityWarning,
+			Category: "println",
+			Message:  "use of the println builtin",
+			SuggestedFixes: []inspector.SuggestedFix{{
+				Message: "Use print",
+				TextEdits: []inspector.TextEdit{{
+					Pos:     ident.Pos(),
+					End:     ident.End(),
+					NewText: []byte("print"),
+				}},
+			}},
+		})
+
+		return true
+	}
+}
+
+func TestNew(t *testing.T) {
+	dir, err := filepath.Abs("_data")
+	assert.Nil(t, err, err)
+
+	a := New("println", "report the calls to println", printlnReporter(nil))
+	assert.Equal(t, "println", a.Name)
+
+	analysistest.RunWithSuggestedFixes(t, dir, a, "a")
+}
+
+func TestFromLead(t *testing.T) {
+	dir, err := filepath.Abs("_data")
+	assert.Nil(t, err, err)
+
+	funcs := 0
+	a := FromLead("println", "report the calls to println", func(pass *analysis.Pass) *inspector.Lead {
+		lead := inspector.New().WithReporters(printlnReporter(pass.TypesInfo))
+		inspector.On(lead, func(_ *ast.FuncDecl) bool {
+			funcs++
+			return true
+		})
+
+		return lead
+	})
+
+	analysistest.Run(t, dir, a, "a")
+	assert.Equal(t, 2, funcs)
+}
+
+func TestFromLead_Edits(t *testing.T) {
+	dir, err := filepath.Abs("_data")
+	assert.Nil(t, err, err)
+
+	a := FromLead("edits", "edit the calls", func(_ *analysis.Pass) *inspector.Lead {
+		return inspector.New().WithCursors(func(cursor *inspector.Cursor) bool {
+			switch node := cursor.Node().(type) {
+			case *ast.Ident:
+				if node.Name == "println" {
+					cursor.Replace(ast.NewIdent("print"))
+				}
+			case *ast.ExprStmt:
+				if call := node.X.(*ast.CallExpr); call.Fun.(*ast.Ident).Name == "panic" {
+					cursor.Delete()
+				}
+			}
+
+			return true
+		})
+	})
+
+	analysistest.RunWithSuggestedFixes(t, dir, a, "b")
+}
+
+func TestFromLead_DeleteListElement(t *testing.T) {
+	dir, err := filepath.Abs("_data")
+	assert.Nil(t, err, err)
+
+	a := FromLead("delete", "delete the list elements", func(_ *analysis.Pass) *inspector.Lead {
+		return inspector.New().WithCursors(func(cursor *inspector.Cursor) bool {
+			switch node := cursor.Node().(type) {
+			case *ast.BasicLit:
+				if node.Value == "2" {
+					cursor.Delete()
+				}
+			case *ast.Field:
+				if len(node.Names) > 0 && node.Names[0].Name == "b" {
+					cursor.Delete()
+				}
+			}
+
+			return true
+		})
+	})
+
+	analysistest.RunWithSuggestedFixes(t, dir, a, "c")
+}
+
+func TestDiagnostic(t *testing.T) {
+	diagnostic := Diagnostic(inspector.Diagnostic{
+		Pos:      1,
+		End:      2,
+		Severity: inspector.SeverityError,
+		Category: "category",
+		Message:  "message",
+		SuggestedFixes: []inspector.SuggestedFix{{
+			Message:   "fix",
+			TextEdits: []inspector.TextEdit{{Pos: 1, End: 2, NewText: []byte("x")}},
+		}},
+	})
+
+	assert.Equal(t, analysis.Diagnostic{
+		Pos:      1,
+		End:      2,
+		Category: "category",
+		Message:  "message",
+		SuggestedFixes: []analysis.SuggestedFix{{
+			Message:   "fix",
+			TextEdits: []analysis.TextEdit{{Pos: 1, End: 2, NewText: []byte("x")}},
+		}},
+	}, diagnostic)
+}
@@ -0,0 +1,243 @@
+package inspector
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/negrel/asttk/pkg/parse"
+)
+
+// EditKind is the kind of an Edit.
+type EditKind int
+
+const (
+	// EditReplace is used for Cursor.Replace.
+	EditReplace EditKind = iota
+	// EditDelete is used for Cursor.Delete.
+	EditDelete
+	// EditInsertBefore is used for Cursor.InsertBefore.
+	EditInsertBefore
+	// EditInsertAfter is used for Cursor.InsertAfter.
+	EditInsertAfter
+)
+
+func (k EditKind) String() string {
+	switch k {
+	case EditReplace:
+		return "replace"
+	case EditDelete:
+		return "delete"
+	case EditInsertBefore:
+		return "insert before"
+	case EditInsertAfter:
+		return "insert after"
+	}
+
+	return fmt.Sprintf("EditKind(%d)", int(k))
+}
+
+// Edit is a Cursor edit recorded by a Lead, see Lead.WithRecordedEdits.
+type Edit struct {
+	Kind EditKind
+	// Node is the node the Cursor was on.
+	Node ast.Node
+	// New is the replacement or the inserted node, nil for a deletion.
+	New ast.Node
+
+	// prev and next are the siblings of Node in the slice that contains it,
+	// they are used to remove the list separators of a deleted node.
+	prev, next ast.Node
+}
+
+// CursorInspector is an Inspector that receive a Cursor describing the current
+// node instead of the node itself. Returning false skip the children of the
+// current node, like any Inspector. The Cursor is only valid during the call.
+type CursorInspector func(cursor *Cursor) bool
+
+// Cursor describes a node encountered during the inspection. Information about
+// the node and its ancestors is available from the Node, Parent, Path, Name and
+// Index methods. Replace, Delete, InsertBefore and InsertAfter can be used to
+// edit the AST without disrupting the inspection.
+//
+// Nodes removed or replaced are not walked any further and the remaining
+// inspectors are not called on them. Nodes inserted are not walked.
+type Cursor struct {
+	lead   *Lead
+	cursor *astutil.Cursor
+	node   ast.Node
+	done   bool
+}
+
+// WithCommentMap set the comment map updated by the Cursor edits, comments of
+// replaced nodes are associated to their replacement and comments of deleted
+// nodes are removed from the map. Replacement nodes should reuse the position
+// of the replaced node to keep their comments in place.
+func (l *Lead) WithCommentMap(comments ast.CommentMap) *Lead {
+	l.comments = comments
+
+	return l
+}
+
+// WithRecordedEdits make the Cursor edits recorded instead of applied and return
+// the Lead, the AST isn't modified. The inspection goes on as if the edits were
+// applied: removed and replaced nodes are not walked any further. See Edits.
+func (l *Lead) WithRecordedEdits() *Lead {
+	l.recordEdits = true
+
+	return l
+}
+
+// Edits return the Cursor edits recorded during the last inspection of a Lead
+// created with WithRecordedEdits, in the order they were made.
+func (l *Lead) Edits() []Edit {
+	return l.edits
+}
+
+// WithCursors register the given CursorInspector on the Lead after the already
+// registered inspectors and return the Lead.
+func (l *Lead) WithCursors(inspectors ...CursorInspector) *Lead {
+	for _, inspector := range inspectors {
+		l.add(&entry{
+			inspect: inspector,
+		})
+	}
+
+	return l
+}
+
+func (c *Cursor) reset(cursor *astutil.Cursor, node ast.Node) {
+	c.cursor = cursor
+	c.node = node
+	c.done = false
+}
+
+// Node return the current node.
+func (c *Cursor) Node() ast.Node {
+	return c.node
+}
+
+// Parent return the parent of the current node or nil if the current node is
+// the root of the inspected AST.
+func (c *Cursor) Parent() ast.Node {
+	if length := len(c.lead.path); length > 0 {
+		return c.lead.path[length-1]
+	}
+
+	return nil
+}
+
+// Path return the ancestors of the current node, from the root of the inspected
+// AST to the parent of the current node. The returned slice must not be modified.
+func (c *Cursor) Path() []ast.Node {
+	return c.lead.path
+}
+
+// Name return the name of the parent node field that contains the current node.
+func (c *Cursor) Name() string {
+	if c.cursor == nil {
+		return ""
+	}
+
+	return c.cursor.Name()
+}
+
+// Index return the index >= 0 of the current node in the slice of nodes that
+// contains it, or a value < 0 if the current node is not part of a slice.
+func (c *Cursor) Index() int {
+	if c.cursor == nil {
+		return -1
+	}
+
+	return c.cursor.Index()
+}
+
+// Replace replaces the current node with n.
+func (c *Cursor) Replace(n ast.Node) {
+	if !c.record(EditReplace, n) {
+		c.astutilCursor().Replace(n)
+
+		if c.lead.comments != nil {
+			parse.MoveComments(c.lead.comments, c.node, n)
+		}
+	}
+
+	c.node = n
+	c.done = true
+}
+
+// Delete deletes the current node from its containing slice. If the current
+// node is not part of a slice, Delete panics.
+func (c *Cursor) Delete() {
+	if !c.record(EditDelete, nil) {
+		c.astutilCursor().Delete()
+		delete(c.lead.comments, c.node)
+	}
+
+	c.done = true
+}
+
+// InsertBefore inserts n before the current node in its containing slice.
+// If the current node is not part of a slice, InsertBefore panics.
+func (c *Cursor) InsertBefore(n ast.Node) {
+	if !c.record(EditInsertBefore, n) {
+		c.astutilCursor().InsertBefore(n)
+	}
+}
+
+// InsertAfter inserts n after the current node in its containing slice.
+// If the current node is not part of a slice, InsertAfter panics.
+func (c *Cursor) InsertAfter(n ast.Node) {
+	if !c.record(EditInsertAfter, n) {
+		c.astutilCursor().InsertAfter(n)
+	}
+}
+
+// record records the given edit of the current node if the Lead records the
+// edits, it return false if the edit must be applied.
+func (c *Cursor) record(kind EditKind, n ast.Node) bool {
+	if !c.lead.recordEdits {
+		return false
+	}
+
+	if kind != EditReplace && c.Index() < 0 {
+		panic(fmt.Sprintf("cursor can't %v a node that is not part of a slice", kind))
+	}
+
+	edit := Edit{
+		Kind: kind,
+		Node: c.node,
+		New:  n,
+	}
+	if index := c.Index(); index >= 0 {
+		edit.prev, edit.next = siblings(c.cursor.Parent(), c.Name(), index)
+	}
+	c.lead.edits = append(c.lead.edits, edit)
+
+	return true
+}
+
+// siblings return the nodes before and after the given index in the slice
+// field of parent with the given name, nil if there is none.
+func siblings(parent ast.Node, name string, index int) (prev, next ast.Node) {
+	list := reflect.Indirect(reflect.ValueOf(parent)).FieldByName(name)
+
+	if index > 0 {
+		prev = list.Index(index - 1).Interface().(ast.Node)
+	}
+	if index+1 < list.Len() {
+		next = list.Index(index + 1).Interface().(ast.Node)
+	}
+
+	return prev, next
+}
+
+func (c *Cursor) astutilCursor() *astutil.Cursor {
+	if c.cursor == nil {
+		panic("cursor can't edit the AST of a Lead used as an Inspector")
+	}
+
+	return c.cursor
+}
@@ -0,0 +1,322 @@
+package inspector
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/negrel/asttk/pkg/parse"
+)
+
+func TestCursor_Position(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", helloWorld, parser.AllErrors)
+	assert.Nil(t, err)
+
+	var path []ast.Node
+	var parent ast.Node
+	var name string
+	var index int
+
+	lInspector := New().WithCursors(func(cursor *Cursor) bool {
+		lit, isBasicLit := cursor.Node().(*ast.BasicLit)
+		if !isBasicLit || lit.Value != `"World"` {
+			return true
+		}
+
+		path = append(path, cursor.Path()...)
+		parent = cursor.Parent()
+		name = cursor.Name()
+		index = cursor.Index()
+
+		return true
+	})
+	lInspector.Inspect(file)
+
+	// File > FuncDecl > BlockStmt > ExprStmt > CallExpr
+	assert.Len(t, path, 5)
+	assert.Equal(t, file, path[0])
+	assert.IsType(t, &ast.CallExpr{}, parent)
+	assert.Equal(t, parent, path[len(path)-1])
+	assert.Equal(t, "Args", name)
+	assert.Equal(t, 0, index)
+}
+
+func TestCursor_Edit(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", helloWorld, parser.AllErrors)
+	assert.Nil(t, err)
+
+	isCallTo := func(node ast.Node, name string) bool {
+		stmt, isExprStmt := node.(*ast.ExprStmt)
+		if !isExprStmt {
+			return false
+		}
+
+		call, isCall := stmt.X.(*ast.CallExpr)
+		if !isCall {
+			return false
+		}
+
+		ident, isIdent := call.Fun.(*ast.Ident)
+		return isIdent && ident.Name == name
+	}
+
+	deleteGreetCall := func(cursor *Cursor) bool {
+		if isCallTo(cursor.Node(), "greet") {
+			cursor.InsertBefore(&ast.ExprStmt{X: &ast.CallExpr{Fun: ast.NewIdent("before")}})
+			cursor.InsertAfter(&ast.ExprStmt{X: &ast.CallExpr{Fun: ast.NewIdent("after")}})
+			cursor.Delete()
+		}
+
+		return true
+	}
+
+	replaceName := func(cursor *Cursor) bool {
+		if ident, isIdent := cursor.Node().(*ast.Ident); isIdent && ident.Name == "name" {
+			cursor.Replace(ast.NewIdent("who"))
+		}
+
+		return true
+	}
+
+	var visited []string
+	recordIdents := func(node ast.Node) bool {
+		if ident, isIdent := node.(*ast.Ident); isIdent {
+			visited = append(visited, ident.Name)
+		}
+
+		return true
+	}
+
+	lInspector := New().WithCursors(deleteGreetCall, replaceName)
+	On(lInspector, func(ident *ast.Ident) bool {
+		return recordIdents(ident)
+	})
+	lInspector.Inspect(file)
+
+	buf := &bytes.Buffer{}
+	err = format.Node(buf, fset, file)
+	assert.Nil(t, err)
+
+	src := buf.String()
+	assert.Contains(t, src, "\tbefore()\n\tafter()\n")
+	assert.NotContains(t, src, "greet(\"World\")")
+	assert.Contains(t, src, "func greet(who string) {\n\tfmt.Println(\"Hello\", who)\n}")
+
+	// Removed and replaced nodes are not walked.
+	assert.Equal(t, []string{"main", "main", "greet", "string", "fmt", "Println"}, visited)
+}
+
+func TestCursor_ReplaceWithCommentMap(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", `package main
+
+// main doc.
+func main() {
+	greet("World") // greet the world
+}
+`, parser.ParseComments)
+	assert.Nil(t, err)
+
+	comments := ast.NewCommentMap(fset, file, file.Comments)
+
+	var replaced, replacement ast.Node
+	lInspector := New().WithCommentMap(comments).WithCursors(func(cursor *Cursor) bool {
+		if stmt, isExprStmt := cursor.Node().(*ast.ExprStmt); isExprStmt {
+			replaced = stmt
+			replacement = &ast.ExprStmt{X: &ast.CallExpr{
+				Fun:    ast.NewIdent("println"),
+				Lparen: stmt.Pos() + token.Pos(len("greet")),
+				Args:   stmt.X.(*ast.CallExpr).Args,
+				Rparen: stmt.End() - 1,
+			}}
+			cursor.Replace(replacement)
+		}
+
+		return true
+	})
+	lInspector.Inspect(file)
+
+	assert.NotContains(t, comments, replaced)
+	assert.Len(t, comments[replacement], 1)
+	assert.Equal(t, "// greet the world", comments[replacement][0].List[0].Text)
+}
+
+func TestCursor_DeleteWithCommentMap(t *testing.T) {
+	filePath := filepath.Join("..", "parse", "_data", "virtual", "comments.go")
+	file, err := parse.FileFromSource(filePath, []byte(`package main
+
+// main doc.
+func main() {
+	greet("World") // greet the world
+	println("Bye")
+}
+
+// greet doc.
+func greet(name string) {
+	println("Hello", name)
+}
+`))
+	assert.Nil(t, err, err)
+
+	var deleted []ast.Node
+	lInspector := New().WithCommentMap(file.CommentMap()).WithCursors(func(cursor *Cursor) bool {
+		switch node := cursor.Node().(type) {
+		case *ast.ExprStmt:
+			if call := node.X.(*ast.CallExpr); call.Fun.(*ast.Ident).Name == "greet" {
+				deleted = append(deleted, node)
+				cursor.Delete()
+			}
+		case *ast.FuncDecl:
+			if node.Name.Name == "greet" {
+				deleted = append(deleted, node)
+				cursor.Delete()
+			}
+		}
+
+		return true
+	})
+	lInspector.Apply(file.AST())
+
+	assert.Len(t, deleted, 2)
+	for _, node := range deleted {
+		assert.NotContains(t, file.CommentMap(), node)
+	}
+
+	src, err := file.Bytes()
+	assert.Nil(t, err, err)
+	// The printer keeps a blank line in place of the deleted statement.
+	assert.Equal(t, `package main
+
+// main doc.
+func main() {
+
+	println("Bye")
+}
+`, string(src))
+}
+
+func TestLead_WithRecordedEdits(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", helloWorld, parser.AllErrors)
+	assert.Nil(t, err)
+
+	var before bytes.Buffer
+	assert.Nil(t, format.Node(&before, fset, file))
+
+	var visited []string
+	lead := New().WithRecordedEdits().WithCursors(func(cursor *Cursor) bool {
+		if ident, isIdent := cursor.Node().(*ast.Ident); isIdent {
+			visited = append(visited, ident.Name)
+		}
+
+		if stmt, isExprStmt := cursor.Node().(*ast.ExprStmt); isExprStmt {
+			if ident, isIdent := stmt.X.(*ast.CallExpr).Fun.(*ast.Ident); isIdent && ident.Name == "greet" {
+				cursor.InsertBefore(&ast.ExprStmt{X: &ast.CallExpr{Fun: ast.NewIdent("before")}})
+				cursor.Delete()
+			}
+		}
+
+		return true
+	})
+	lead.Apply(file)
+
+	// The AST isn't modified but the deleted node isn't walked.
+	var after bytes.Buffer
+	assert.Nil(t, format.Node(&after, fset, file))
+	assert.Equal(t, before.String(), after.String())
+	assert.Equal(t, []string{"main", "main", "greet", "name", "string", "fmt", "Println", "name"}, visited)
+
+	edits := lead.Edits()
+	assert.Len(t, edits, 2)
+	assert.Equal(t, EditInsertBefore, edits[0].Kind)
+	assert.Equal(t, "delete", edits[1].Kind.String())
+
+	insert, err := edits[0].TextEdit(fset)
+	assert.Nil(t, err, err)
+	assert.Equal(t, edits[0].Node.Pos(), insert.Pos)
+	assert.Equal(t, insert.Pos, insert.End)
+	assert.Equal(t, "before()\n", string(insert.NewText))
+
+	remove, err := edits[1].TextEdit(fset)
+	assert.Nil(t, err, err)
+	assert.Equal(t, edits[1].Node.End(), remove.End)
+	assert.Empty(t, remove.NewText)
+
+	// Edits are cleared between inspections.
+	lead.Apply(&ast.File{Name: ast.NewIdent("main")})
+	assert.Empty(t, lead.Edits())
+}
+
+func TestCursor_EditPostVisit(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", helloWorld, parser.AllErrors)
+	assert.Nil(t, err)
+
+	depth, maxDepth := 0, 0
+	countDepth := func(node ast.Node) bool {
+		if node == nil {
+			depth--
+		} else {
+			depth++
+		}
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+
+		return true
+	}
+
+	entered, left := 0, 0
+	hooks := Hooks{
+		Enter: func(_ ast.Node) bool {
+			entered++
+			return true
+		},
+		Leave: func(_ ast.Node) {
+			left++
+		},
+	}
+
+	// Skip the string literals, they must not receive a post-visit.
+	skipped := 0
+	skipLits := func(node ast.Node) bool {
+		if _, isLit := node.(*ast.BasicLit); isLit {
+			return false
+		}
+		if node == nil {
+			skipped++
+		}
+
+		return true
+	}
+
+	edit := func(cursor *Cursor) bool {
+		switch node := cursor.Node().(type) {
+		case *ast.ExprStmt:
+			if call, isCall := node.X.(*ast.CallExpr); isCall {
+				if ident, isIdent := call.Fun.(*ast.Ident); isIdent && ident.Name == "greet" {
+					cursor.Delete()
+				}
+			}
+		case *ast.BasicLit:
+			cursor.Replace(&ast.BasicLit{Kind: node.Kind, Value: `"Hi"`})
+		}
+
+		return true
+	}
+
+	New(countDepth, skipLits).WithHooks(hooks).WithCursors(edit).Inspect(file)
+
+	assert.Equal(t, 0, depth)
+	assert.NotZero(t, maxDepth)
+	assert.Equal(t, entered, left)
+	assert.Equal(t, entered-2, skipped)
+}
@@ -0,0 +1,268 @@
+package inspector
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/token"
+
+	"github.com/negrel/asttk/pkg/parse"
+)
+
+// Severity is the severity of a Diagnostic.
+type Severity int
+
+const (
+	// SeverityInfo is used for diagnostics that don't require any action.
+	SeverityInfo Severity = iota
+	// SeverityWarning is used for diagnostics of code that is likely wrong.
+	SeverityWarning
+	// SeverityError is used for diagnostics of code that must be fixed.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// Diagnostic is a finding reported by a ReportInspector.
+type Diagnostic struct {
+	// Pos and End are the range of the source code the diagnostic is about,
+	// End is optional.
+	Pos, End token.Pos
+	Severity Severity
+	// Category is an optional identifier of the rule that produced the
+	// diagnostic.
+	Category string
+	Message  string
+	// SuggestedFixes are optional alternative fixes of the diagnostic.
+	SuggestedFixes []SuggestedFix
+}
+
+// SuggestedFix is a fix of a Diagnostic made of text edits.
+type SuggestedFix struct {
+	Message   string
+	TextEdits []TextEdit
+}
+
+// TextEdit replaces the source code between Pos and End with NewText. Pos and
+// End are equal for an insertion.
+type TextEdit struct {
+	Pos, End token.Pos
+	NewText  []byte
+}
+
+// ReplaceEdit return a TextEdit replacing the source code of the given node
+// with the formatted replacement node.
+func ReplaceEdit(fset *token.FileSet, node, replacement ast.Node) (TextEdit, error) {
+	buf := &bytes.Buffer{}
+
+	err := format.Node(buf, fset, replacement)
+	if err != nil {
+		return TextEdit{}, err
+	}
+
+	return TextEdit{
+		Pos:     node.Pos(),
+		End:     node.End(),
+		NewText: buf.Bytes(),
+	}, nil
+}
+
+// TextEdit return the TextEdit applying the given Edit to the source code. The
+// inserted nodes are separated from the current node by a newline if they are
+// statements or specs, a blank line if they are declarations and a comma
+// otherwise. The comma separating a deleted expression or field from its
+// siblings is deleted with it.
+func (e Edit) TextEdit(fset *token.FileSet) (TextEdit, error) {
+	switch e.Kind {
+	case EditReplace:
+		return ReplaceEdit(fset, e.Node, e.New)
+	case EditDelete:
+		return e.deleteEdit(), nil
+	}
+
+	buf := &bytes.Buffer{}
+	err := format.Node(buf, fset, e.New)
+	if err != nil {
+		return TextEdit{}, err
+	}
+
+	separator := ", "
+	switch e.New.(type) {
+	case ast.Stmt, ast.Spec:
+		separator = "\n"
+	case ast.Decl:
+		separator = "\n\n"
+	}
+
+	if e.Kind == EditInsertBefore {
+		return TextEdit{
+			Pos:     e.Node.Pos(),
+			End:     e.Node.Pos(),
+			NewText: append(buf.Bytes(), separator...),
+		}, nil
+	}
+
+	return TextEdit{
+		Pos:     e.Node.End(),
+		End:     e.Node.End(),
+		NewText: append([]byte(separator), buf.Bytes()...),
+	}, nil
+}
+
+func (e Edit) deleteEdit() TextEdit {
+	edit := TextEdit{Pos: e.Node.Pos(), End: e.Node.End()}
+
+	switch e.Node.(type) {
+	case ast.Stmt, ast.Spec, ast.Decl:
+		return edit
+	}
+
+	if e.next != nil {
+		edit.End = e.next.Pos()
+	} else if e.prev != nil {
+		edit.Pos = e.prev.End()
+	}
+
+	return edit
+}
+
+// ReportInspector is an Inspector that can report diagnostics through the given
+// Pass. Returning false skip the children of the current node, like any Inspector.
+type ReportInspector func(node ast.Node, pass *Pass) bool
+
+// Pass records the diagnostics reported by the ReportInspectors of a Lead.
+type Pass struct {
+	// File is the inspected file, nil unless it was set with Lead.WithFile.
+	File *parse.GoFile
+
+	diagnostics []Diagnostic
+}
+
+// Report records the given diagnostic.
+func (p *Pass) Report(diagnostic Diagnostic) {
+	p.diagnostics = append(p.diagnostics, diagnostic)
+}
+
+// Reportf records a diagnostic about the given node with a formatted message.
+func (p *Pass) Reportf(node ast.Node, severity Severity, category, format string, args ...interface{}) {
+	p.Report(Diagnostic{
+		Pos:      node.Pos(),
+		End:      node.End(),
+		Severity: severity,
+		Category: category,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Diagnostics return the recorded diagnostics, in the order they were reported.
+func (p *Pass) Diagnostics() []Diagnostic {
+	return p.diagnostics
+}
+
+// WithFile set the file given to the ReportInspectors through Pass.File and
+// return the Lead.
+func (l *Lead) WithFile(file *parse.GoFile) *Lead {
+	l.pass.File = file
+
+	return l
+}
+
+// WithReporters register the given ReportInspector on the Lead after the already
+// registered inspectors and return the Lead.
+func (l *Lead) WithReporters(inspectors ...ReportInspector) *Lead {
+	for _, inspector := range inspectors {
+		l.add(&entry{
+			inspect: func(cursor *Cursor) bool {
+				return inspector(cursor.Node(), &l.pass)
+			},
+		})
+	}
+
+	return l
+}
+
+// Diagnostics return the diagnostics reported by the ReportInspectors of the
+// Lead during its last inspection.
+func (l *Lead) Diagnostics() []Diagnostic {
+	return l.pass.Diagnostics()
+}
+
+// FileDiagnostics is the diagnostics reported on a single file by Diagnose.
+type FileDiagnostics struct {
+	Package     *parse.GoPackage
+	File        *parse.GoFile
+	Diagnostics []Diagnostic
+}
+
+// PackageDiagnostics is the diagnostics reported on the files of a single
+// package by Diagnose.
+type PackageDiagnostics struct {
+	Package *parse.GoPackage
+	Files   []FileDiagnostics
+}
+
+// Diagnostics return the diagnostics of every file of the package, in the file
+// order.
+func (p PackageDiagnostics) Diagnostics() []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, file := range p.Files {
+		diagnostics = append(diagnostics, file.Diagnostics...)
+	}
+
+	return diagnostics
+}
+
+// ByPackage group the given file diagnostics per package, in the package order.
+func ByPackage(files []FileDiagnostics) []PackageDiagnostics {
+	var packages []PackageDiagnostics
+	index := make(map[*parse.GoPackage]int)
+
+	for _, file := range files {
+		i, ok := index[file.Package]
+		if !ok {
+			i = len(packages)
+			index[file.Package] = i
+			packages = append(packages, PackageDiagnostics{Package: file.Package})
+		}
+
+		packages[i].Files = append(packages[i].Files, file)
+	}
+
+	return packages
+}
+
+// Diagnose run the given ReportInspectors on every file of the given package,
+// and of its sub-packages if recursive is true, concurrently like
+// InspectPackage. Every file gets its own Pass. It return the diagnostics of
+// every file, in the package and file order, see ByPackage to group them per
+// package.
+func Diagnose(pkg *parse.GoPackage, recursive bool, inspectors ...ReportInspector) []FileDiagnostics {
+	results := InspectPackage(pkg, recursive, func(file *parse.GoFile) (Inspector, func() []Diagnostic) {
+		lead := New().WithFile(file).WithReporters(inspectors...)
+
+		return lead.inspect, lead.Diagnostics
+	})
+
+	diagnostics := make([]FileDiagnostics, len(results))
+	for i, result := range results {
+		diagnostics[i] = FileDiagnostics{
+			Package:     result.Package,
+			File:        result.File,
+			Diagnostics: result.Values[0],
+		}
+	}
+
+	return diagnostics
+}
@@ -0,0 +1,137 @@
+package inspector
+
+import (
+	"go/ast"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/negrel/asttk/pkg/parse"
+)
+
+// noPrintln report the calls to the println builtin.
+func noPrintln(node ast.Node, pass *Pass) bool {
+	call, isCall := node.(*ast.CallExpr)
+	if !isCall {
+		return true
+	}
+
+	if ident, isIdent := call.Fun.(*ast.Ident); isIdent && ident.Name == "println" {
+		pass.Report(Diagnostic{
+			Pos:      call.Pos(),
+			End:      call.End(),
+			Severity: SeverityWarning,
+			Category: "println",
+			Message:  "use of the println builtin",
+			SuggestedFixes: []SuggestedFix{{
+				Message: "Remove the call",
+				TextEdits: []TextEdit{{
+					Pos: call.Pos(),
+					End: call.End(),
+				}},
+			}},
+		})
+	}
+
+	return true
+}
+
+func TestLead_WithReporters(t *testing.T) {
+	file, err := parse.FileFromSource(filepath.Join("_data", "diagnostic", "main.go"), []byte(`package main
+
+func main() {
+	println("Hello")
+	print("World")
+	println()
+}
+`))
+	assert.Nil(t, err, err)
+
+	var files []*parse.GoFile
+	lead := New().WithFile(file).WithReporters(noPrintln, func(node ast.Node, pass *Pass) bool {
+		if _, isFile := node.(*ast.File); isFile {
+			files = append(files, pass.File)
+			pass.Reportf(node, SeverityInfo, "", "%v declarations", len(file.AST().Decls))
+		}
+
+		return true
+	})
+	lead.Inspect(file.AST())
+
+	assert.Equal(t, []*parse.GoFile{file}, files)
+
+	diagnostics := lead.Diagnostics()
+	assert.Len(t, diagnostics, 3)
+
+	assert.Equal(t, "1 declarations", diagnostics[0].Message)
+	assert.Equal(t, SeverityInfo, diagnostics[0].Severity)
+
+	assert.Equal(t, "use of the println builtin", diagnostics[1].Message)
+	assert.Equal(t, "warning", diagnostics[1].Severity.String())
+	assert.Equal(t, 4, file.FileSet().Position(diagnostics[1].Pos).Line)
+	assert.Len(t, diagnostics[1].SuggestedFixes, 1)
+
+	assert.Equal(t, 6, file.FileSet().Position(diagnostics[2].Pos).Line)
+
+	// Diagnostics of the previous inspection are cleared.
+	lead.Inspect(file.AST())
+	assert.Len(t, lead.Diagnostics(), 3)
+}
+
+func TestDiagnose(t *testing.T) {
+	dir := filepath.Join("..", "parse", "_data", "pkg", "pkg_with_subpkg")
+	pkg, err := parse.Package(dir, true)
+	assert.Nil(t, err, err)
+
+	// Report every function declaration.
+	funcDecls := func(node ast.Node, pass *Pass) bool {
+		if funcDecl, isFuncDecl := node.(*ast.FuncDecl); isFuncDecl {
+			pass.Reportf(funcDecl.Name, SeverityError, "func", "%v in %v", funcDecl.Name.Name, pass.File.Name())
+		}
+
+		return true
+	}
+
+	results := Diagnose(pkg, true, funcDecls, noPrintln)
+	assert.Len(t, results, 2)
+
+	assert.Equal(t, pkg, results[0].Package)
+	assert.Equal(t, pkg.Files[0], results[0].File)
+	assert.Len(t, results[0].Diagnostics, 1)
+	assert.Equal(t, "Greet in greet.go", results[0].Diagnostics[0].Message)
+
+	assert.Equal(t, pkg.SubPkgs()[0], results[1].Package)
+	assert.Len(t, results[1].Diagnostics, 1)
+	assert.Equal(t, "Print in log.go", results[1].Diagnostics[0].Message)
+	assert.Equal(t, SeverityError, results[1].Diagnostics[0].Severity)
+
+	packages := ByPackage(results)
+	assert.Len(t, packages, 2)
+	assert.Equal(t, pkg, packages[0].Package)
+	assert.Equal(t, results[:1], packages[0].Files)
+	assert.Equal(t, pkg.SubPkgs()[0], packages[1].Package)
+	assert.Equal(t, results[1].Diagnostics, packages[1].Diagnostics())
+}
+
+func TestReplaceEdit(t *testing.T) {
+	file, err := parse.FileFromSource(filepath.Join("_data", "diagnostic", "main.go"), []byte(`package main
+
+func main() {
+	println("Hello")
+}
+`))
+	assert.Nil(t, err, err)
+
+	call := file.AST().Decls[0].(*ast.FuncDecl).Body.List[0].(*ast.ExprStmt).X.(*ast.CallExpr)
+	replacement := &ast.CallExpr{
+		Fun:  ast.NewIdent("print"),
+		Args: []ast.Expr{ast.NewIdent("greeting")},
+	}
+
+	edit, err := ReplaceEdit(file.FileSet(), call, replacement)
+	assert.Nil(t, err, err)
+	assert.Equal(t, call.Pos(), edit.Pos)
+	assert.Equal(t, call.End(), edit.End)
+	assert.Equal(t, "print(greeting)", string(edit.NewText))
+}
@@ -0,0 +1,36 @@
+package inspector
+
+import (
+	"go/ast"
+)
+
+// Hooks define an inspector with a pre-order and a post-order callback.
+//
+// Enter is called when the walk enters a node, returning false skip the children
+// of the node. Leave is called with the node being left once its children were
+// visited, it isn't called if Enter returned false. Unlike Inspector, hooks never
+// receive nil nodes. Both callbacks are optional.
+type Hooks struct {
+	Enter func(node ast.Node) bool
+	Leave func(node ast.Node)
+}
+
+// WithHooks register the given Hooks on the Lead after the already registered
+// inspectors and return the Lead.
+func (l *Lead) WithHooks(hooks ...Hooks) *Lead {
+	for _, h := range hooks {
+		enter := h.Enter
+		if enter == nil {
+			enter = func(_ ast.Node) bool { return true }
+		}
+
+		l.add(&entry{
+			inspect: func(cursor *Cursor) bool {
+				return enter(cursor.Node())
+			},
+			leave: h.Leave,
+		})
+	}
+
+	return l
+}
@@ -0,0 +1,106 @@
+package inspector
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLead_WithHooks(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", `
+	package main
+
+	func main() {
+		a := 1
+		{
+			a := 2
+			{
+				b := a
+				_ = b
+			}
+		}
+		_ = a
+	}
+`, parser.AllErrors)
+	assert.Nil(t, err)
+
+	// Block depth counter
+	depth, maxDepth := 0, 0
+	blockDepth := Hooks{
+		Enter: func(node ast.Node) bool {
+			if _, isBlock := node.(*ast.BlockStmt); isBlock {
+				depth++
+				if depth > maxDepth {
+					maxDepth = depth
+				}
+			}
+
+			return true
+		},
+		Leave: func(node ast.Node) {
+			if _, isBlock := node.(*ast.BlockStmt); isBlock {
+				depth--
+			}
+		},
+	}
+
+	// Variable shadowing detector
+	scopes := []map[string]bool{{}}
+	var shadowed []string
+	shadowing := Hooks{
+		Enter: func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.BlockStmt:
+				scopes = append(scopes, map[string]bool{})
+
+			case *ast.AssignStmt:
+				if n.Tok != token.DEFINE {
+					break
+				}
+
+				for _, lhs := range n.Lhs {
+					name := lhs.(*ast.Ident).Name
+					for _, scope := range scopes[:len(scopes)-1] {
+						if scope[name] {
+							shadowed = append(shadowed, name)
+						}
+					}
+
+					scopes[len(scopes)-1][name] = true
+				}
+			}
+
+			return true
+		},
+		Leave: func(node ast.Node) {
+			if _, isBlock := node.(*ast.BlockStmt); isBlock {
+				scopes = scopes[:len(scopes)-1]
+			}
+		},
+	}
+
+	// Leave is not called when Enter return false.
+	var left []ast.Node
+	skipFuncs := Hooks{
+		Enter: func(node ast.Node) bool {
+			_, isFunc := node.(*ast.FuncDecl)
+			return !isFunc
+		},
+		Leave: func(node ast.Node) {
+			assert.NotNil(t, node)
+			left = append(left, node)
+		},
+	}
+
+	New().WithHooks(blockDepth, shadowing, skipFuncs).Inspect(file)
+
+	assert.Equal(t, 0, depth)
+	assert.Equal(t, 3, maxDepth)
+	assert.Equal(t, []string{"a"}, shadowed)
+	assert.Len(t, scopes, 1)
+	assert.Equal(t, []ast.Node{file.Name, file}, left)
+}
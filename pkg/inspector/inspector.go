@@ -2,15 +2,48 @@ package inspector
 
 import (
 	"go/ast"
+	"reflect"
+
+	"golang.org/x/tools/go/ast/astutil"
 )
 
+// Inspector is a function called on every node of the inspected AST, it follows
+// the ast.Inspect semantics: returning false skip the children of the current
+// node and, if the children were visited, the Inspector is called with a nil
+// node once the walk leaves the current node. See Hooks for inspectors that
+// need the node being left.
 type Inspector func(node ast.Node) bool
 
 // Lead is the Inspector chief that manage the inspection.
+//
+// Every Inspector of a Lead sees the AST as if it was walked alone with
+// ast.Inspect. When an Inspector returns false, only its own descent into the
+// children of the current node is skipped: the other Inspectors keep walking the
+// subtree and the Inspector comes back when the walk leaves the node. The order
+// of the Inspectors is preserved.
 type Lead struct {
-	active   []Inspector
-	depth    int
-	inactive map[int]map[int]Inspector
+	inspectors []*entry
+	depth      int
+	active     int
+	inactive   map[int][]*entry
+	path       []ast.Node
+	cursor     Cursor
+	comments   ast.CommentMap
+	pass       Pass
+
+	recordEdits bool
+	edits       []Edit
+
+	typed    map[reflect.Type][]*entry
+	typedAny []*entry
+}
+
+type entry struct {
+	inspect func(cursor *Cursor) bool
+	stopped bool
+	// leave is called when the walk leaves a node whose children were
+	// visited, nil if the inspector doesn't need it.
+	leave func(node ast.Node)
 }
 
 // Lieutenant define an Inspector that manage his own Inspectors.
@@ -20,81 +53,163 @@ func Lieutenant(Inspectors ...Inspector) Inspector {
 
 // New return an Inspector Lead.
 func New(inspectors ...Inspector) *Lead {
-	length := len(inspectors)
-	ii := make([]Inspector, length, length+1)
-	for i, inspector := range inspectors {
-		ii[i] = inspector
+	l := &Lead{
+		depth:    0,
+		inactive: make(map[int][]*entry),
+
+		typed: make(map[reflect.Type][]*entry),
 	}
+	l.cursor.lead = l
 
-	return &Lead{
-		active:   ii,
-		depth:    0,
-		inactive: make(map[int]map[int]Inspector),
+	for _, inspector := range inspectors {
+		l.add(inspectorEntry(inspector))
 	}
+
+	return l
 }
 
+func inspectorEntry(inspector Inspector) *entry {
+	return &entry{
+		inspect: func(cursor *Cursor) bool {
+			return inspector(cursor.Node())
+		},
+		leave: func(_ ast.Node) {
+			inspector(nil)
+		},
+	}
+}
+
+func (l *Lead) add(e *entry) {
+	l.inspectors = append(l.inspectors, e)
+	l.active++
+}
+
+// Inspect traverses the given AST with all the Inspectors of the Lead.
 func (l *Lead) Inspect(node ast.Node) {
-	l.depth = 0
-	ast.Inspect(node, l.inspect)
+	l.Apply(node)
 }
 
+// Apply traverses the given AST with all the Inspectors of the Lead and
+// return it, possibly replaced by a CursorInspector.
+func (l *Lead) Apply(root ast.Node) ast.Node {
+	l.reset()
+
+	return astutil.Apply(root, l.pre, l.post)
+}
+
+func (l *Lead) pre(c *astutil.Cursor) bool {
+	// Unlike ast.Inspect, astutil.Apply visits nil children.
+	if c.Node() == nil {
+		return false
+	}
+
+	return l.enter(c, c.Node())
+}
+
+func (l *Lead) post(_ *astutil.Cursor) bool {
+	l.leave()
+
+	return true
+}
+
+// inspect adapt the Lead to the Inspector type.
 func (l *Lead) inspect(node ast.Node) bool {
 	if node == nil {
-		defer func() {
-			l.depth--
-			l.recoverStoppedAt(l.depth)
-		}()
-	} else {
-		defer func() {
-			l.depth++
-		}()
+		l.leave()
+		return true
 	}
 
-	i := -1
-	for index, inspector := range l.active {
-		i++
+	return l.enter(nil, node)
+}
 
-		ok := inspector(node)
-		if !ok {
-			l.stopAt(l.depth, i, index)
-			i--
+func (l *Lead) enter(c *astutil.Cursor, node ast.Node) bool {
+	l.cursor.reset(c, node)
+
+	var accepted []*entry
+	for _, e := range l.inspectors {
+		if l.visit(e) {
+			accepted = append(accepted, e)
 		}
 	}
 
-	if len(l.active) == 0 {
+	l.inspectTyped(node)
+
+	// The node was removed or replaced, the walk won't leave it through a
+	// post-visit: the inspectors that accepted it leave it now.
+	if l.cursor.done {
+		for _, e := range accepted {
+			if e.leave != nil {
+				e.leave(node)
+			}
+		}
+	}
+
+	// Every inspector skip the children of the node or the node was
+	// removed, the walk won't leave it through a post-visit.
+	if l.active == 0 || l.cursor.done {
 		l.recoverStoppedAt(l.depth)
 		return false
 	}
 
+	l.path = append(l.path, node)
+	l.depth++
+
 	return true
 }
 
-func (l *Lead) recoverStoppedAt(depth int) {
-	inactive, ok := l.inactive[depth]
-	if !ok {
-		return
-	}
+func (l *Lead) leave() {
+	l.depth--
+	node := l.path[len(l.path)-1]
+	l.path = l.path[:len(l.path)-1]
 
-	for index, inspector := range inactive {
-		if length := len(l.active); length == 0 || length <= index {
-			l.active = append(l.active, inspector)
-		} else {
-			last := len(l.active) - 1
-			l.active = append(l.active, l.active[last])
-			copy(l.active[index+1:], l.active[index:last])
-			l.active[index] = inspector
+	for _, e := range l.inspectors {
+		if !e.stopped && e.leave != nil {
+			e.leave(node)
 		}
 	}
 
+	l.recoverStoppedAt(l.depth)
+}
+
+// visit call the inspector of the given entry on the current node, it return
+// true if the inspector was called and accepted the node.
+func (l *Lead) visit(e *entry) bool {
+	if e.stopped || l.cursor.done {
+		return false
+	}
+
+	if !e.inspect(&l.cursor) {
+		l.stopAt(l.depth, e)
+		return false
+	}
+
+	return true
+}
+
+func (l *Lead) recoverStoppedAt(depth int) {
+	for _, e := range l.inactive[depth] {
+		e.stopped = false
+	}
+
+	l.active += len(l.inactive[depth])
 	delete(l.inactive, depth)
 }
 
-func (l *Lead) stopAt(depth, i, index int) {
-	inactive, ok := l.inactive[depth]
-	if !ok {
-		return
+func (l *Lead) stopAt(depth int, e *entry) {
+	e.stopped = true
+	l.active--
+	l.inactive[depth] = append(l.inactive[depth], e)
+}
+
+// reset restore the Lead state in case a previous inspection was interrupted
+// and clear the diagnostics and edits recorded during the previous inspection.
+func (l *Lead) reset() {
+	for depth := range l.inactive {
+		l.recoverStoppedAt(depth)
 	}
 
-	inactive[index] = l.active[i]
-	l.active = append(l.active[:i], l.active[i+1:]...)
+	l.depth = 0
+	l.path = l.path[:0]
+	l.pass.diagnostics = nil
+	l.edits = nil
 }
@@ -213,3 +213,201 @@ func TestLead_OrderRemain(t *testing.T) {
 		previousRecord = record
 	}
 }
+
+func TestOn_ConcreteType(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", helloWorld, parser.AllErrors)
+	assert.Nil(t, err)
+
+	var funcs []string
+	lInspector := New()
+	On(lInspector, func(funcDecl *ast.FuncDecl) bool {
+		funcs = append(funcs, funcDecl.Name.Name)
+		return true
+	})
+	lInspector.Inspect(file)
+
+	assert.Equal(t, []string{"main", "greet"}, funcs)
+}
+
+func TestOn_InterfaceType(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", helloWorld, parser.AllErrors)
+	assert.Nil(t, err)
+
+	// Expected
+	expectedStmtCounter := new(counter)
+	ast.Inspect(file, stmtCount(expectedStmtCounter))
+
+	// Actual
+	stmtCounter := new(counter)
+	lInspector := New()
+	On(lInspector, func(stmt ast.Stmt) bool {
+		stmtCounter.value++
+		return true
+	})
+	lInspector.Inspect(file)
+
+	assert.Equal(t, expectedStmtCounter.value, stmtCounter.value)
+}
+
+func TestOn_SkipChildren(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", `
+	package main
+
+	func main() {
+		{
+			{}
+		}
+	}
+
+	func greet() {
+		{}
+	}
+`, parser.AllErrors)
+	assert.Nil(t, err)
+
+	blockCounter := new(counter)
+	identCounter := new(counter)
+	lInspector := New()
+	On(lInspector, func(block *ast.BlockStmt) bool {
+		blockCounter.value++
+		return false
+	})
+	On(lInspector, func(ident *ast.Ident) bool {
+		identCounter.value++
+		return true
+	})
+	lInspector.Inspect(file)
+
+	// Only function bodies are visited.
+	assert.Equal(t, 2, blockCounter.value)
+	// package name and function names.
+	assert.Equal(t, 3, identCounter.value)
+}
+
+type visitRecorder struct {
+	visits []ast.Node
+	stop   func(node ast.Node) bool
+}
+
+func (vr *visitRecorder) inspect(node ast.Node) bool {
+	vr.visits = append(vr.visits, node)
+
+	return node == nil || !vr.stop(node)
+}
+
+func stopOn(types ...ast.Node) func(node ast.Node) bool {
+	return func(node ast.Node) bool {
+		for _, typ := range types {
+			if fmt.Sprintf("%T", typ) == fmt.Sprintf("%T", node) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+func stopEveryNth(nth int) func(node ast.Node) bool {
+	c := 0
+
+	return func(node ast.Node) bool {
+		c++
+		return c%nth == 0
+	}
+}
+
+func TestLead_SkipSemantics(t *testing.T) {
+	tests := []struct {
+		name  string
+		stops []func() func(node ast.Node) bool
+	}{
+		{
+			name: "NeverStop",
+			stops: []func() func(node ast.Node) bool{
+				func() func(node ast.Node) bool { return stopOn() },
+			},
+		},
+		{
+			name: "AlwaysStop",
+			stops: []func() func(node ast.Node) bool{
+				func() func(node ast.Node) bool { return func(ast.Node) bool { return true } },
+				func() func(node ast.Node) bool { return stopOn() },
+			},
+		},
+		{
+			name: "StopAtSameDepth",
+			stops: []func() func(node ast.Node) bool{
+				func() func(node ast.Node) bool { return stopOn(&ast.FuncDecl{}) },
+				func() func(node ast.Node) bool { return stopOn(&ast.FuncDecl{}) },
+				func() func(node ast.Node) bool { return stopOn(&ast.FuncDecl{}, &ast.GenDecl{}) },
+				func() func(node ast.Node) bool { return stopOn() },
+			},
+		},
+		{
+			name: "StopAtDifferentDepth",
+			stops: []func() func(node ast.Node) bool{
+				func() func(node ast.Node) bool { return stopOn(&ast.BlockStmt{}) },
+				func() func(node ast.Node) bool { return stopOn(&ast.CallExpr{}) },
+				func() func(node ast.Node) bool { return stopOn(&ast.Ident{}) },
+				func() func(node ast.Node) bool { return stopOn(&ast.FieldList{}) },
+			},
+		},
+		{
+			name: "EveryInspectorStop",
+			stops: []func() func(node ast.Node) bool{
+				func() func(node ast.Node) bool { return stopOn(&ast.FuncDecl{}) },
+				func() func(node ast.Node) bool { return stopOn(&ast.FuncDecl{}, &ast.GenDecl{}) },
+			},
+		},
+		{
+			name: "StopEveryNthNode",
+			stops: []func() func(node ast.Node) bool{
+				func() func(node ast.Node) bool { return stopEveryNth(2) },
+				func() func(node ast.Node) bool { return stopEveryNth(3) },
+				func() func(node ast.Node) bool { return stopEveryNth(5) },
+				func() func(node ast.Node) bool { return stopEveryNth(7) },
+			},
+		},
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", helloWorld, parser.AllErrors)
+	assert.Nil(t, err)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Expected
+			expected := make([]*visitRecorder, len(test.stops))
+			for i, stop := range test.stops {
+				expected[i] = &visitRecorder{stop: stop()}
+				ast.Inspect(file, expected[i].inspect)
+			}
+
+			// Actual
+			actual := make([]*visitRecorder, len(test.stops))
+			inspectors := make([]Inspector, len(test.stops))
+			for i, stop := range test.stops {
+				actual[i] = &visitRecorder{stop: stop()}
+				inspectors[i] = actual[i].inspect
+			}
+
+			lInspector := New(inspectors...)
+			// Inspecting twice ensure the Lead state is restored.
+			for run := 0; run < 2; run++ {
+				for i, recorder := range actual {
+					recorder.visits = nil
+					recorder.stop = test.stops[i]()
+				}
+
+				lInspector.Inspect(file)
+
+				for i := range test.stops {
+					assert.Equal(t, expected[i].visits, actual[i].visits, "inspector %v", i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package inspector
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/negrel/asttk/pkg/parse"
+)
+
+// Factory return a fresh Inspector for the given file and a function returning
+// the result of the inspection once the file was inspected.
+type Factory[R any] func(file *parse.GoFile) (inspector Inspector, result func() R)
+
+// Result is the result of the inspection of a single file. Values contains the
+// result of each Factory, in order.
+type Result[R any] struct {
+	Package *parse.GoPackage
+	File    *parse.GoFile
+	Values  []R
+}
+
+// InspectPackage inspect every file of the given package, including its test
+// files and external test package, and of its sub-packages if recursive is
+// true, concurrently. Every Factory is called once
+// per file and the inspectors of a file run in a single Lead. Results are
+// returned in the package and file order.
+//
+// Files are inspected concurrently, inspectors must not share mutable state
+// across files without synchronization.
+func InspectPackage[R any](pkg *parse.GoPackage, recursive bool, factories ...Factory[R]) []Result[R] {
+	results := collectFiles[R](pkg, recursive, nil)
+
+	wg := sync.WaitGroup{}
+	workers := make(chan struct{}, runtime.GOMAXPROCS(0))
+
+	for i := range results {
+		wg.Add(1)
+		workers <- struct{}{}
+
+		go func(result *Result[R]) {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+
+			inspectFile(result, factories)
+		}(&results[i])
+	}
+
+	wg.Wait()
+
+	return results
+}
+
+// Merge merge the results of every file, per Factory.
+func Merge[R any](results []Result[R], merge func(acc, value R) R) []R {
+	var merged []R
+
+	for _, result := range results {
+		if merged == nil {
+			merged = make([]R, len(result.Values))
+		}
+
+		for i, value := range result.Values {
+			merged[i] = merge(merged[i], value)
+		}
+	}
+
+	return merged
+}
+
+func collectFiles[R any](pkg *parse.GoPackage, recursive bool, results []Result[R]) []Result[R] {
+	for _, file := range pkg.AllFiles() {
+		results = append(results, Result[R]{
+			Package: pkg,
+			File:    file,
+		})
+	}
+
+	if xtest := pkg.XTest(); xtest != nil {
+		results = collectFiles(xtest, false, results)
+	}
+
+	if !recursive {
+		return results
+	}
+
+	for _, subPkg := range pkg.SubPkgs() {
+		results = collectFiles(subPkg, true, results)
+	}
+
+	return results
+}
+
+func inspectFile[R any](result *Result[R], factories []Factory[R]) {
+	inspectors := make([]Inspector, len(factories))
+	getters := make([]func() R, len(factories))
+
+	for i, factory := range factories {
+		inspectors[i], getters[i] = factory(result.File)
+	}
+
+	New(inspectors...).Inspect(result.File.AST())
+
+	result.Values = make([]R, len(getters))
+	for i, get := range getters {
+		result.Values[i] = get()
+	}
+}
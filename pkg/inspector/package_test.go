@@ -0,0 +1,59 @@
+package inspector
+
+import (
+	"go/ast"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/negrel/asttk/pkg/parse"
+)
+
+func countFactory[N ast.Node]() Factory[int] {
+	return func(_ *parse.GoFile) (Inspector, func() int) {
+		count := 0
+
+		inspector := func(node ast.Node) bool {
+			if _, ok := node.(N); ok {
+				count++
+			}
+
+			return true
+		}
+
+		return inspector, func() int { return count }
+	}
+}
+
+func TestInspectPackage(t *testing.T) {
+	dir := filepath.Join("..", "parse", "_data", "pkg", "pkg_with_subpkg")
+	pkg, err := parse.Package(dir, true)
+	assert.Nil(t, err, err)
+
+	sum := func(acc, value int) int { return acc + value }
+
+	results := InspectPackage(pkg, false, countFactory[*ast.FuncDecl](), countFactory[*ast.CallExpr]())
+	assert.Len(t, results, 1)
+	assert.Equal(t, pkg, results[0].Package)
+	assert.Equal(t, pkg.Files[0], results[0].File)
+	assert.Equal(t, []int{1, 2}, Merge(results, sum))
+
+	results = InspectPackage(pkg, true, countFactory[*ast.FuncDecl](), countFactory[*ast.CallExpr]())
+	assert.Len(t, results, 2)
+	assert.Equal(t, pkg.SubPkgs()[0], results[1].Package)
+	assert.Equal(t, []int{1, 2}, results[1].Values)
+	assert.Equal(t, []int{2, 4}, Merge(results, sum))
+}
+
+func TestInspectPackage_Tests(t *testing.T) {
+	dir := filepath.Join("..", "parse", "_data", "pkg", "tested")
+	pkg, err := parse.Package(dir, false, parse.WithTests(), parse.WithXTest())
+	assert.Nil(t, err, err)
+
+	results := InspectPackage(pkg, false, countFactory[*ast.FuncDecl]())
+	assert.Len(t, results, 3)
+	assert.Equal(t, pkg.TestFiles[0], results[1].File)
+	assert.Equal(t, pkg.XTest(), results[2].Package)
+	assert.Equal(t, []int{3}, Merge(results, func(acc, value int) int { return acc + value }))
+}
@@ -0,0 +1,49 @@
+package inspector
+
+import (
+	"go/ast"
+	"reflect"
+)
+
+// On register an Inspector on the given Lead that is only called on nodes of
+// type N. N can be a concrete node type (*ast.FuncDecl, *ast.CallExpr...) or an
+// interface (ast.Expr, ast.Stmt...). Concrete node types are dispatched through a
+// per-type table so the Lead doesn't call the inspector on every node.
+//
+// Like any Inspector, returning false skip the children of the current node.
+// Typed inspectors are called after the untyped one and never receive nil nodes.
+func On[N ast.Node](l *Lead, inspector func(node N) bool) {
+	typ := reflect.TypeOf((*N)(nil)).Elem()
+	l.active++
+
+	if typ.Kind() == reflect.Interface {
+		l.typedAny = append(l.typedAny, &entry{
+			inspect: func(cursor *Cursor) bool {
+				n, ok := cursor.Node().(N)
+				if !ok {
+					return true
+				}
+
+				return inspector(n)
+			},
+		})
+
+		return
+	}
+
+	l.typed[typ] = append(l.typed[typ], &entry{
+		inspect: func(cursor *Cursor) bool {
+			return inspector(cursor.Node().(N))
+		},
+	})
+}
+
+func (l *Lead) inspectTyped(node ast.Node) {
+	for _, e := range l.typed[reflect.TypeOf(node)] {
+		l.visit(e)
+	}
+
+	for _, e := range l.typedAny {
+		l.visit(e)
+	}
+}
@@ -0,0 +1,4 @@
+package broken
+
+// Broken is fine, its sub-packages are not.
+func Broken() {}
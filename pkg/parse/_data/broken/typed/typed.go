@@ -0,0 +1,3 @@
+package typed
+
+var Typed int = "typed"
@@ -0,0 +1,5 @@
+//go:build custom
+
+package tagged
+
+func Custom() {}
@@ -0,0 +1,4 @@
+package inner
+
+// Name is the name of the module.
+const Name = "module"
@@ -0,0 +1,8 @@
+package module
+
+import "example.com/module/inner"
+
+// Name return the name of the module.
+func Name() string {
+	return inner.Name
+}
@@ -1,17 +1,189 @@
 package parse
 
 import (
+	"context"
+	"go/token"
+	"os"
+	"strings"
+
 	"golang.org/x/tools/go/packages"
 )
 
-// Config is the config used to load file/packages.
-// Config.Dir is overwritten before loading files.
+// Config is the base config used to load file/packages, it is copied by every
+// call. Config.Dir and Config.Overlay are overwritten before loading files.
+// Prefer the Option of each call to changing this variable, the options don't
+// affect concurrent calls.
 var Config = packages.Config{
 	Mode: packages.NeedName | packages.NeedSyntax |
 		packages.NeedImports | packages.NeedCompiledGoFiles |
 		packages.NeedFiles | packages.NeedTypes |
-		packages.NeedDeps,
+		packages.NeedTypesInfo | packages.NeedDeps,
 
 	Tests:      false,
 	BuildFlags: []string{},
 }
+
+// Option configure a single call to File, Package and their variants.
+type Option func(options *loadOptions)
+
+type loadOptions struct {
+	tests      bool
+	xtest      bool
+	ctx        context.Context
+	buildTags  []string
+	env        []string
+	syntaxOnly bool
+	fset       *token.FileSet
+	dir        string
+	strict     bool
+	result     *LoadResult
+}
+
+// WithTests include the test files of the package itself, they are exposed by
+// GoPackage.TestFiles and type-checked with the other files of the package.
+func WithTests() Option {
+	return func(options *loadOptions) {
+		options.tests = true
+	}
+}
+
+// WithXTest load the external test package (package xxx_test) in the directory
+// of the package, it is exposed by GoPackage.XTest.
+func WithXTest() Option {
+	return func(options *loadOptions) {
+		options.xtest = true
+	}
+}
+
+// WithContext set the context of the call, canceling it interrupt the loading.
+func WithContext(ctx context.Context) Option {
+	return func(options *loadOptions) {
+		options.ctx = ctx
+	}
+}
+
+// WithBuildTags set the build tags used to select the files to load.
+func WithBuildTags(tags ...string) Option {
+	return func(options *loadOptions) {
+		options.buildTags = append(options.buildTags, tags...)
+	}
+}
+
+// WithEnv add the given "key=value" variables to the environment of the go
+// command, GOOS and GOARCH for example.
+func WithEnv(env ...string) Option {
+	return func(options *loadOptions) {
+		options.env = append(options.env, env...)
+	}
+}
+
+// WithSyntaxOnly load the syntax of the files without type-checking them nor
+// loading the dependencies, which is much faster. The types information of the
+// loaded packages is nil.
+func WithSyntaxOnly() Option {
+	return func(options *loadOptions) {
+		options.syntaxOnly = true
+	}
+}
+
+// WithFileSet set the token.FileSet used to parse the files, it can be shared
+// between several calls.
+func WithFileSet(fset *token.FileSet) Option {
+	return func(options *loadOptions) {
+		options.fset = fset
+	}
+}
+
+// WithDir set the directory from which the relative patterns given to Load are
+// resolved, the working directory by default.
+func WithDir(dir string) Option {
+	return func(options *loadOptions) {
+		options.dir = dir
+	}
+}
+
+// WithStrict make the call fail if a sub-package or a package matched by a
+// pattern can't be loaded. By default, they are skipped and the other packages
+// are returned.
+func WithStrict() Option {
+	return func(options *loadOptions) {
+		options.strict = true
+	}
+}
+
+// WithResult set the LoadResult filled with the directories skipped by the
+// call and the reason they were skipped.
+func WithResult(result *LoadResult) Option {
+	return func(options *loadOptions) {
+		options.result = result
+	}
+}
+
+func newLoadOptions(opts []Option) loadOptions {
+	options := loadOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
+
+// config return a copy of Config with the options applied.
+func (options loadOptions) config() packages.Config {
+	config := Config
+	config.Tests = config.Tests || options.tests || options.xtest
+
+	if options.ctx != nil {
+		config.Context = options.ctx
+	}
+
+	if len(options.buildTags) > 0 {
+		config.BuildFlags = append(append([]string{}, config.BuildFlags...), "-tags="+strings.Join(options.buildTags, ","))
+	}
+
+	if len(options.env) > 0 {
+		env := config.Env
+		if env == nil {
+			env = os.Environ()
+		}
+		config.Env = append(append([]string{}, env...), options.env...)
+	}
+
+	if options.dir != "" {
+		config.Dir = options.dir
+	}
+
+	if options.fset != nil {
+		config.Fset = options.fset
+	}
+
+	if options.syntaxOnly {
+		config.Mode &^= packages.NeedTypes | packages.NeedTypesInfo | packages.NeedDeps | packages.NeedImports
+	}
+
+	return config
+}
+
+// fileSet return the FileSet of the options or a new one.
+func (options loadOptions) fileSet() *token.FileSet {
+	if options.fset != nil {
+		return options.fset
+	}
+
+	return token.NewFileSet()
+}
+
+// report record the skipped directories in the LoadResult of the options. It
+// return the error of the first skipped directory if the options are strict.
+func (options loadOptions) report(errs map[string]*PackageError) error {
+	skippedDirs := skipped(errs)
+	if options.result != nil {
+		options.result.Skipped = skippedDirs
+	}
+
+	if options.strict && len(skippedDirs) != 0 {
+		return skippedDirs[0]
+	}
+
+	return nil
+}
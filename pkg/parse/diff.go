@@ -0,0 +1,82 @@
+package parse
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Diff return a unified diff of the source code the file was loaded from
+// against the current AST of the file. The diff is empty if the file is
+// unchanged.
+func (f *GoFile) Diff() ([]byte, error) {
+	if f.src == nil {
+		return nil, fmt.Errorf("the source code of %v is unavailable", f.path)
+	}
+
+	content, err := f.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Equal(f.src, content) {
+		return nil, nil
+	}
+
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        splitLines(f.src),
+		B:        splitLines(content),
+		FromFile: f.path + ".orig",
+		ToFile:   f.path,
+		Context:  3,
+	})
+
+	return []byte(diff), err
+}
+
+// Diff return the unified diff of every file of the package, including its
+// test files and its external test package, and of the sub-packages if
+// recursive is true. See GoFile.Diff.
+func (p *GoPackage) Diff(recursive bool) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	files := p.AllFiles()
+	if p.xtest != nil {
+		files = append(files, p.xtest.AllFiles()...)
+	}
+
+	for _, file := range files {
+		diff, err := file.Diff()
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(diff)
+	}
+
+	if !recursive {
+		return buf.Bytes(), nil
+	}
+	for _, subPkg := range p.subPkgs {
+		diff, err := subPkg.Diff(true)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(diff)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// splitLines split the given source in lines that keep their line ending.
+func splitLines(src []byte) []string {
+	lines := strings.SplitAfter(string(src), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return lines
+}
@@ -0,0 +1,61 @@
+package parse
+
+import (
+	"go/ast"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFile_Diff(t *testing.T) {
+	filePath, _ := filepath.Abs(filepath.Join("_data", "virtual", "main.go"))
+
+	goFile, err := FileFromSource(filePath, []byte(`package main
+
+func main() {
+	println("Hello world")
+}
+`))
+	assert.Nil(t, err, err)
+
+	diff, err := goFile.Diff()
+	assert.Nil(t, err, err)
+	assert.Empty(t, diff)
+
+	goFile.AST().Decls[0].(*ast.FuncDecl).Name.Name = "run"
+
+	diff, err = goFile.Diff()
+	assert.Nil(t, err, err)
+	assert.Equal(t, `--- `+filePath+`.orig
++++ `+filePath+`
+@@ -1,5 +1,5 @@
+ package main
+ 
+-func main() {
++func run() {
+ 	println("Hello world")
+ }
+`, string(diff))
+}
+
+func TestPkg_Diff(t *testing.T) {
+	pkg, err := Package(filepath.Join("_data", "pkg", "pkg_with_subpkg"), true)
+	assert.Nil(t, err, err)
+
+	diff, err := pkg.Diff(true)
+	assert.Nil(t, err, err)
+	assert.Empty(t, diff)
+
+	subPkg := pkg.SubPkgs()[0]
+	subPkg.Files[0].AST().Decls[1].(*ast.FuncDecl).Name.Name = "Println"
+
+	diff, err = pkg.Diff(false)
+	assert.Nil(t, err, err)
+	assert.Empty(t, diff)
+
+	diff, err = pkg.Diff(true)
+	assert.Nil(t, err, err)
+	assert.Contains(t, string(diff), "+++ "+subPkg.Files[0].Path()+"\n")
+	assert.Contains(t, string(diff), "-func Print(msg string) {\n+func Println(msg string) {\n")
+}
@@ -0,0 +1,185 @@
+package parse
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ErrorKind is the kind of an Error.
+type ErrorKind int
+
+const (
+	// UnknownError is used for errors that don't match any other kind.
+	UnknownError ErrorKind = iota
+	// NoGoFilesError is used for directories without any go file to load.
+	NoGoFilesError
+	// BuildConstraintsError is used for directories whose go files are all
+	// excluded by build constraints.
+	BuildConstraintsError
+	// ListError is used for the other errors of the go command, an invalid
+	// import path or a missing directory for example.
+	ListError
+	// ParseError is used for syntax errors.
+	ParseError
+	// TypeError is used for type-checking errors.
+	TypeError
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case UnknownError:
+		return "unknown error"
+	case NoGoFilesError:
+		return "no go files"
+	case BuildConstraintsError:
+		return "build constraints"
+	case ListError:
+		return "list error"
+	case ParseError:
+		return "parse error"
+	case TypeError:
+		return "type error"
+	}
+
+	return fmt.Sprintf("ErrorKind(%d)", int(k))
+}
+
+// Error is an error found while loading a package.
+type Error struct {
+	// Pos is the position of the error, it is invalid if the error isn't
+	// related to a file.
+	Pos  token.Position
+	Kind ErrorKind
+	Msg  string
+}
+
+func (e Error) Error() string {
+	if e.Pos.IsValid() {
+		return fmt.Sprintf("%v: %v", e.Pos, e.Msg)
+	}
+
+	return e.Msg
+}
+
+// PackageError is returned when the package in a directory can't be loaded, it
+// contains every error found in the package.
+type PackageError struct {
+	Dir    string
+	Errors []Error
+}
+
+func (e *PackageError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+
+	return fmt.Sprintf("%v: %v", e.Dir, strings.Join(msgs, "\n"))
+}
+
+// LoadResult list the directories skipped while loading packages, it is filled
+// by the calls given the WithResult option.
+type LoadResult struct {
+	// Skipped are the directories that couldn't be loaded, sorted by
+	// directory.
+	Skipped []*PackageError
+}
+
+// newPackageError convert the errors of the go/packages driver.
+func newPackageError(dir string, pkgErrors []packages.Error) *PackageError {
+	if len(pkgErrors) == 0 {
+		return nil
+	}
+
+	errs := make([]Error, len(pkgErrors))
+	for i, pkgErr := range pkgErrors {
+		errs[i] = Error{
+			Pos:  parsePosition(pkgErr.Pos),
+			Kind: errorKind(pkgErr),
+			Msg:  pkgErr.Msg,
+		}
+	}
+
+	return &PackageError{Dir: dir, Errors: errs}
+}
+
+func errorKind(pkgErr packages.Error) ErrorKind {
+	switch pkgErr.Kind {
+	case packages.ParseError:
+		return ParseError
+	case packages.TypeError:
+		return TypeError
+	case packages.ListError:
+		switch {
+		case strings.HasPrefix(pkgErr.Msg, "build constraints exclude all Go files"):
+			return BuildConstraintsError
+		case strings.HasPrefix(pkgErr.Msg, "no Go files"), strings.HasPrefix(pkgErr.Msg, "no non-test Go files"):
+			return NoGoFilesError
+		}
+		return ListError
+	}
+
+	return UnknownError
+}
+
+// parsePosition parse the "file:line:column" positions of the go/packages
+// driver, the line and column are optional.
+func parsePosition(pos string) token.Position {
+	position := token.Position{Filename: pos}
+
+	for _, field := range []*int{&position.Column, &position.Line} {
+		i := strings.LastIndexByte(position.Filename, ':')
+		if i < 0 {
+			break
+		}
+
+		n, err := strconv.Atoi(position.Filename[i+1:])
+		if err != nil {
+			break
+		}
+
+		*field = n
+		position.Filename = position.Filename[:i]
+	}
+
+	// A "file:line" position.
+	if position.Line == 0 && position.Column != 0 {
+		position.Line, position.Column = position.Column, 0
+	}
+
+	return position
+}
+
+// newParseError convert the errors returned by go/parser.
+func newParseError(dir string, err error) *PackageError {
+	list, ok := err.(scanner.ErrorList)
+	if !ok {
+		return &PackageError{Dir: dir, Errors: []Error{{Kind: UnknownError, Msg: err.Error()}}}
+	}
+
+	errs := make([]Error, len(list))
+	for i, scanErr := range list {
+		errs[i] = Error{Pos: scanErr.Pos, Kind: ParseError, Msg: scanErr.Msg}
+	}
+
+	return &PackageError{Dir: dir, Errors: errs}
+}
+
+// skipped return the errors of the given directories sorted by directory.
+func skipped(errs map[string]*PackageError) []*PackageError {
+	result := make([]*PackageError, 0, len(errs))
+	for _, err := range errs {
+		result = append(result, err)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Dir < result[j].Dir
+	})
+
+	return result
+}
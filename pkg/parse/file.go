@@ -7,10 +7,10 @@ import (
 	"go/format"
 	"go/token"
 	"io"
-	"os"
+	"io/ioutil"
 	"path/filepath"
-
-	"golang.org/x/tools/go/packages"
+	"sort"
+	"strings"
 )
 
 // GoFile define a parsed go file.
@@ -18,46 +18,83 @@ type GoFile struct {
 	path string
 	ast  *ast.File
 	fset *token.FileSet
+	pkg  *GoPackage
+	// src is the source code the file was parsed from.
+	src []byte
+
+	comments ast.CommentMap
+	// loaded are the comment groups of the file when it was loaded.
+	loaded map[*ast.CommentGroup]bool
+}
+
+// File parse the file at the given path and return a new *GoFile. Test files
+// (*_test.go) are loaded with the test files of their package, or with the
+// external test package.
+func File(filePath string, opts ...Option) (*GoFile, error) {
+	return loadFile(filePath, nil, newLoadOptions(opts))
 }
 
-// File parse the file at the given path and return a new *GoFile.
-// Test file (*_test.go) are not supported.
-func File(filePath string) (*GoFile, error) {
+// FileFromSource parse the given source code as if it was the content of the
+// file at the given path and return a new *GoFile. The file doesn't need to exist,
+// the other files of its package are loaded from disk.
+func FileFromSource(filePath string, src []byte, opts ...Option) (*GoFile, error) {
 	filePath, err := filepath.Abs(filePath)
 	if err != nil {
 		return nil, err
 	}
-	dir, filename := filepath.Split(filePath)
 
+	return loadFile(filePath, map[string][]byte{filePath: src}, newLoadOptions(opts))
+}
+
+// FileFromReader is like FileFromSource but read the source code from the
+// given io.Reader.
+func FileFromReader(filePath string, r io.Reader, opts ...Option) (*GoFile, error) {
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return FileFromSource(filePath, src, opts...)
+}
+
+func loadFile(filePath string, overlay map[string][]byte, options loadOptions) (*GoFile, error) {
+	filePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, filename := filepath.Split(filePath)
 	if filepath.Ext(filename) != ".go" {
 		return nil, fmt.Errorf("the given file path should end with a \".go\" extension")
 	}
+	if strings.HasSuffix(filename, "_test.go") {
+		options.tests = true
+		options.xtest = true
+	}
 
 	// Loading packages
-	config := Config
-	config.Dir = dir
-
-	pkgs, err := packages.Load(&config)
+	dir = filepath.Clean(dir)
+	pkgs, err := load(dir, []string{dir}, overlay, options)
 	if err != nil {
 		return nil, err
 	}
 
-	var astFile *ast.File = nil
-	for _, pkg := range pkgs {
-		for i, goFile := range pkg.GoFiles {
-			if goFile == filePath {
-				astFile = pkg.Syntax[i]
-			}
-		}
-	}
-	if astFile == nil {
+	goPkg, _ := buildPackage(pkgs, dir, nil, overlay, options)
+	if goPkg == nil {
 		return nil, fmt.Errorf("file not found")
 	}
 
-	return &GoFile{
-		path: filePath,
-		ast:  astFile,
-	}, nil
+	files := goPkg.AllFiles()
+	if goPkg.xtest != nil {
+		files = append(files, goPkg.xtest.AllFiles()...)
+	}
+	for _, file := range files {
+		if file.path == filePath {
+			return file, nil
+		}
+	}
+
+	return nil, fmt.Errorf("file not found")
 }
 
 // Path return the go file absolute path.
@@ -80,9 +117,112 @@ func (f *GoFile) AST() *ast.File {
 	return f.ast
 }
 
-// Fprint "pretty-print" the AST of the file to output.
+// CommentMap return the comment map of the file, it associates the comment
+// groups of the file to the nodes they belong to. Editors should keep it up to
+// date when they replace or remove nodes so the comments follow them, see
+// MoveComments.
+func (f *GoFile) CommentMap() ast.CommentMap {
+	return f.comments
+}
+
+// MoveComments associate the comments of the node from to the node to, see
+// MoveComments.
+func (f *GoFile) MoveComments(from, to ast.Node) {
+	MoveComments(f.comments, from, to)
+}
+
+// MoveComments associate the comments of the node from to the node to in the
+// given comment map. The comments keep their position, the node to should be
+// at the position of the node from.
+func MoveComments(comments ast.CommentMap, from, to ast.Node) {
+	if groups, ok := comments[from]; ok {
+		comments[to] = append(comments[to], groups...)
+		delete(comments, from)
+	}
+}
+
+// Fprint "pretty-print" the AST of the file to output. The comments
+// associated to nodes removed from the AST are not printed, unless a node of
+// the AST still refers to them as its documentation or line comment.
 func (f *GoFile) Fprint(output io.Writer) error {
-	return format.Node(output, f.fset, f.ast)
+	file := *f.ast
+	file.Comments = f.liveComments()
+
+	return format.Node(output, f.fset, &file)
+}
+
+// liveComments return the comments of the file without the ones of the nodes
+// no longer part of the AST, sorted by position.
+func (f *GoFile) liveComments() []*ast.CommentGroup {
+	if f.comments == nil {
+		return f.ast.Comments
+	}
+
+	live := make(map[*ast.CommentGroup]bool)
+	for _, groups := range f.comments.Filter(f.ast) {
+		for _, group := range groups {
+			live[group] = true
+		}
+	}
+	ast.Inspect(f.ast, func(node ast.Node) bool {
+		for _, group := range nodeComments(node) {
+			live[group] = true
+		}
+
+		return true
+	})
+
+	mapped := make(map[*ast.CommentGroup]bool)
+	for _, groups := range f.comments {
+		for _, group := range groups {
+			mapped[group] = true
+		}
+	}
+
+	comments := make([]*ast.CommentGroup, 0, len(f.ast.Comments))
+	for _, group := range f.ast.Comments {
+		if (mapped[group] || f.loaded[group]) && !live[group] {
+			continue
+		}
+		comments = append(comments, group)
+	}
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].Pos() < comments[j].Pos()
+	})
+
+	return comments
+}
+
+// nodeComments return the documentation and line comments referred by the
+// given node.
+func nodeComments(node ast.Node) []*ast.CommentGroup {
+	var groups []*ast.CommentGroup
+
+	switch n := node.(type) {
+	case *ast.File:
+		groups = append(groups, n.Doc)
+	case *ast.GenDecl:
+		groups = append(groups, n.Doc)
+	case *ast.FuncDecl:
+		groups = append(groups, n.Doc)
+	case *ast.Field:
+		groups = append(groups, n.Doc, n.Comment)
+	case *ast.ImportSpec:
+		groups = append(groups, n.Doc, n.Comment)
+	case *ast.ValueSpec:
+		groups = append(groups, n.Doc, n.Comment)
+	case *ast.TypeSpec:
+		groups = append(groups, n.Doc, n.Comment)
+	}
+
+	result := groups[:0]
+	for _, group := range groups {
+		if group != nil {
+			result = append(result, group)
+		}
+	}
+
+	return result
 }
 
 // Bytes convert the AST of the file as an array of byte.
@@ -97,20 +237,80 @@ func (f *GoFile) Bytes() ([]byte, error) {
 // WriteFile method write the GoFile source code in the file
 // at the given path.
 func (f *GoFile) WriteFile(path string) error {
-	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0755)
+	_, err := f.Write(path, WriteOptions{})
+
+	return err
+}
+
+// Write atomically write the GoFile source code in the file at the given path.
+// The file is left untouched if its content is already up to date.
+func (f *GoFile) Write(path string, options WriteOptions) (WriteResult, error) {
+	content, err := f.Bytes()
 	if err != nil {
-		return err
+		return WriteResult{}, err
 	}
 
-	return f.Fprint(file)
+	result := WriteResult{
+		Path:    path,
+		Content: content,
+		Changed: changed(path, content),
+	}
+
+	if options.DryRun || !result.Changed {
+		return result, nil
+	}
+
+	return result, writeFile(path, content)
+}
+
+// Package return the package the file belongs to.
+func (f *GoFile) Package() *GoPackage {
+	return f.pkg
 }
 
-// FileSet return a token.FileSet if the GoFile belong to a GoPackage
-// and nil otherwise.
+// FileSet return the token.FileSet the file was parsed with, it is shared
+// with the other files of its package.
 func (f *GoFile) FileSet() *token.FileSet {
-	if f.fset != nil {
-		return f.fset
+	return f.fset
+}
+
+// Position return the position of the given node in the file. The String method
+// of token.Position format it as file:line:column.
+func (f *GoFile) Position(node ast.Node) token.Position {
+	return f.fset.Position(node.Pos())
+}
+
+// Offsets return the byte offsets of the start and the end of the given node in
+// the file. An error is returned if the node has no position in the file.
+func (f *GoFile) Offsets(node ast.Node) (start, end int, err error) {
+	tokFile := f.fset.File(f.ast.Package)
+	if tokFile == nil {
+		return 0, 0, fmt.Errorf("%v has no position information", f.path)
+	}
+
+	pos, endPos := node.Pos(), node.End()
+	base, size := tokFile.Base(), tokFile.Size()
+	if !pos.IsValid() || int(pos) < base || int(endPos) > base+size || endPos < pos {
+		return 0, 0, fmt.Errorf("the given node is not part of %v", f.path)
+	}
+
+	return tokFile.Offset(pos), tokFile.Offset(endPos), nil
+}
+
+// Source return the source code spanned by the given node, as it was when the
+// file was loaded. Nodes added or moved since then don't have a meaningful source.
+func (f *GoFile) Source(node ast.Node) ([]byte, error) {
+	if f.src == nil {
+		return nil, fmt.Errorf("the source code of %v is unavailable", f.path)
+	}
+
+	start, end, err := f.Offsets(node)
+	if err != nil {
+		return nil, err
+	}
+	if end > len(f.src) {
+		return nil, fmt.Errorf("the given node is out of the source code of %v", f.path)
 	}
 
-	return nil
+	return f.src[start:end], nil
 }
@@ -1,7 +1,9 @@
 package parse
 
 import (
+	"go/ast"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -35,4 +37,158 @@ func TestFile_NewFile(t *testing.T) {
 	assert.Equal(t, filepath.Dir(filePath), goFile.Dir())
 	assert.Equal(t, filepath.Base(filePath), goFile.Name())
 	assert.NotNil(t, goFile.AST())
+	assert.NotNil(t, goFile.FileSet())
+
+	assert.NotNil(t, goFile.Package())
+	assert.Equal(t, "pkg_with_subpkg", goFile.Package().Types().Name())
+	assert.Contains(t, goFile.Package().Files, goFile)
+}
+
+func TestFile_FileFromSource(t *testing.T) {
+	filePath := filepath.Join(".", "_data", "file", "virtual.go")
+	filePath, _ = filepath.Abs(filePath)
+
+	src := `package pkg_with_subpkg
+
+func GreetWorld() {
+	Greet("World")
+}
+`
+	goFile, err := FileFromSource(filePath, []byte(src))
+	assert.Nil(t, err, err)
+
+	assert.Equal(t, filePath, goFile.Path())
+	assert.Equal(t, "GreetWorld", goFile.AST().Decls[0].(*ast.FuncDecl).Name.Name)
+
+	bytes, err := goFile.Bytes()
+	assert.Nil(t, err, err)
+	assert.Equal(t, src, string(bytes))
+}
+
+func TestFile_FileFromReader(t *testing.T) {
+	filePath := filepath.Join(".", "_data", "virtual", "main.go")
+
+	src := "package main\n\nfunc main() {\n}\n"
+	goFile, err := FileFromReader(filePath, strings.NewReader(src))
+	assert.Nil(t, err, err)
+	assert.Equal(t, "main.go", goFile.Name())
+
+	bytes, err := goFile.Bytes()
+	assert.Nil(t, err, err)
+	assert.Equal(t, src, string(bytes))
+}
+
+func TestFile_CommentMap(t *testing.T) {
+	filePath := filepath.Join(".", "_data", "virtual", "comments.go")
+
+	goFile, err := FileFromSource(filePath, []byte(`package main
+
+// main doc.
+func main() {
+	greet("World") // greet the world
+}
+
+// greet doc.
+func greet(name string) {
+	// print greeting
+	println("Hello", name)
+}
+
+// hello doc.
+func hello() {}
+`))
+	assert.Nil(t, err, err)
+	assert.NotNil(t, goFile.CommentMap())
+
+	decls := goFile.AST().Decls
+
+	// Replace main with a copy.
+	newMain := *decls[0].(*ast.FuncDecl)
+	newMain.Name = ast.NewIdent("newMain")
+	newMain.Name.NamePos = decls[0].(*ast.FuncDecl).Name.NamePos
+	goFile.MoveComments(decls[0], &newMain)
+
+	// Replace hello without moving the comments, the copy still refers to
+	// its doc comment.
+	newHello := *decls[2].(*ast.FuncDecl)
+
+	// Remove greet.
+	goFile.AST().Decls = []ast.Decl{&newMain, &newHello}
+
+	comments := len(goFile.AST().Comments)
+	bytes, err := goFile.Bytes()
+	assert.Nil(t, err, err)
+	// Printing doesn't edit the comments of the file.
+	assert.Len(t, goFile.AST().Comments, comments)
+
+	assert.Equal(t, `package main
+
+// main doc.
+func newMain() {
+	greet("World") // greet the world
+}
+
+// hello doc.
+func hello() {}
+`, string(bytes))
+}
+
+func TestFile_Position(t *testing.T) {
+	filePath := filepath.Join(".", "_data", "file", "greet.go")
+	filePath, _ = filepath.Abs(filePath)
+
+	goFile, err := File(filePath)
+	assert.Nil(t, err, err)
+
+	greet := goFile.AST().Decls[1].(*ast.FuncDecl)
+
+	position := goFile.Position(greet.Name)
+	assert.Equal(t, filePath+":8:6", position.String())
+
+	start, end, err := goFile.Offsets(greet.Name)
+	assert.Nil(t, err, err)
+	assert.Equal(t, start+len("Greet"), end)
+
+	src, err := goFile.Source(greet.Type)
+	assert.Nil(t, err, err)
+	assert.Equal(t, "func Greet(names ...string)", string(src))
+
+	// Nodes without position.
+	_, _, err = goFile.Offsets(ast.NewIdent("foo"))
+	assert.NotNil(t, err)
+	_, err = goFile.Source(ast.NewIdent("foo"))
+	assert.NotNil(t, err)
+}
+
+func TestFile_SourceFromOverlay(t *testing.T) {
+	filePath := filepath.Join(".", "_data", "virtual", "main.go")
+
+	goFile, err := FileFromSource(filePath, []byte(`package main
+
+func main() {
+	println("Hello world")
+}
+`))
+	assert.Nil(t, err, err)
+
+	body := goFile.AST().Decls[0].(*ast.FuncDecl).Body
+	src, err := goFile.Source(body.List[0])
+	assert.Nil(t, err, err)
+	assert.Equal(t, `println("Hello world")`, string(src))
+	assert.Equal(t, 4, goFile.Position(body.List[0]).Line)
+}
+
+func TestFile_TestFile(t *testing.T) {
+	filePath, _ := filepath.Abs(filepath.Join(".", "_data", "pkg", "tested", "greet_test.go"))
+
+	goFile, err := File(filePath)
+	assert.Nil(t, err, err)
+	assert.Equal(t, filePath, goFile.Path())
+	assert.Contains(t, goFile.Package().TestFiles, goFile)
+
+	filePath, _ = filepath.Abs(filepath.Join(".", "_data", "pkg", "tested", "example_test.go"))
+	goFile, err = File(filePath)
+	assert.Nil(t, err, err)
+	assert.Equal(t, "tested_test", goFile.AST().Name.Name)
+	assert.Equal(t, "tested_test", goFile.Package().Types().Name())
 }
@@ -3,8 +3,11 @@ package parse
 import (
 	"fmt"
 	"go/token"
+	"go/types"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
 
 	"golang.org/x/tools/go/packages"
 )
@@ -15,11 +18,32 @@ type GoPackage struct {
 	path    string
 	subPkgs []*GoPackage
 	Files   []*GoFile
-	fset    *token.FileSet
+	// TestFiles are the test files of the package itself, loaded with the
+	// WithTests option.
+	TestFiles []*GoFile
+	xtest     *GoPackage
+	fset      *token.FileSet
+	types     *types.Package
+	info      *types.Info
 }
 
 // Package parse an entire package at the given path and return a new *GoPackage.
-func Package(pkgPath string, parseSubPkgs bool) (*GoPackage, error) {
+// A *PackageError is returned if the package has errors. The sub-packages that
+// can't be loaded are skipped unless the WithStrict option is given, use the
+// WithResult option to list them.
+//
+// The sub-packages are searched in every sub-directory, including the ones
+// without go files and the nested modules. Like ParseDir and the go command,
+// sub-directories named testdata or starting with "_" or "." are ignored.
+func Package(pkgPath string, parseSubPkgs bool, opts ...Option) (*GoPackage, error) {
+	return loadPackage(pkgPath, parseSubPkgs, nil, newLoadOptions(opts))
+}
+
+// PackageFromOverlay parse the package at the given path using the given
+// virtual files in place of, or in addition to, the files on disk. Virtual
+// files path are either absolute or relative to the package path. The package
+// directory doesn't need to exist.
+func PackageFromOverlay(pkgPath string, files map[string][]byte, parseSubPkgs bool, opts ...Option) (*GoPackage, error) {
 	if pkgPath == "" {
 		return nil, fmt.Errorf("the given path is empty")
 	}
@@ -29,48 +53,107 @@ func Package(pkgPath string, parseSubPkgs bool) (*GoPackage, error) {
 		return nil, err
 	}
 
-	fileInfo, err := os.Stat(pkgPath)
+	overlay := make(map[string][]byte, len(files))
+	for path, src := range files {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(pkgPath, path)
+		}
+
+		overlay[filepath.Clean(path)] = src
+	}
+
+	return loadPackage(pkgPath, parseSubPkgs, overlay, newLoadOptions(opts))
+}
+
+// Load load the packages matching the given go/packages patterns with a single
+// call to the go command: directories, import paths, "./..." or "file=" queries.
+// Relative patterns are resolved from the directory set with WithDir.
+//
+// The packages are returned as a tree, a package whose directory is inside the
+// directory of another matched package is one of its sub-packages. The packages
+// that can't be loaded are skipped unless the WithStrict option is given, use
+// the WithResult option to list them.
+func Load(patterns []string, opts ...Option) ([]*GoPackage, error) {
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("no pattern given")
+	}
+
+	options := newLoadOptions(opts)
+	config := options.config()
+
+	pkgs, err := packages.Load(&config, patterns...)
 	if err != nil {
 		return nil, err
 	}
-	if !fileInfo.IsDir() {
-		return nil, fmt.Errorf("the given path is not a directory")
+
+	goPkgs, errs := buildPackages(pkgs, nil, options)
+	if err := options.report(errs); err != nil {
+		return nil, err
+	}
+	if len(goPkgs) == 0 && len(errs) != 0 {
+		return nil, skipped(errs)[0]
+	}
+	if len(goPkgs) == 0 {
+		return nil, fmt.Errorf("no package matches %v", strings.Join(patterns, " "))
+	}
+
+	return packageTree(goPkgs), nil
+}
+
+func loadPackage(pkgPath string, parseSubPkgs bool, overlay map[string][]byte, options loadOptions) (*GoPackage, error) {
+	if pkgPath == "" {
+		return nil, fmt.Errorf("the given path is empty")
 	}
 
-	config := Config
-	config.Dir = pkgPath
-	pkgs, err := packages.Load(&config)
+	pkgPath, err := filepath.Abs(pkgPath)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, pkg := range pkgs {
-		if len(pkg.GoFiles) == 0 {
-			continue
-		}
+	fileInfo, err := os.Stat(pkgPath)
+	if err != nil && !(os.IsNotExist(err) && overlayHasDir(overlay, pkgPath)) {
+		return nil, err
+	}
+	if err == nil && !fileInfo.IsDir() {
+		return nil, fmt.Errorf("the given path is not a directory")
+	}
 
-		path := filepath.Dir(pkg.GoFiles[0])
-		if pkgPath != path {
-			continue
+	dirs := map[string][]string{pkgPath: {pkgPath}}
+	if parseSubPkgs {
+		dirs, err = packageDirs(pkgPath, overlay)
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		subPkgs := []*GoPackage{}
-		if parseSubPkgs {
-			subPkgs = findSubPkgs(pkgPath)
+	// The directories are loaded with a driver call per module, the packages
+	// share a single FileSet.
+	options.fset = options.fileSet()
+	var pkgs []*packages.Package
+	for modRoot, modDirs := range dirs {
+		if modRoot == "" {
+			modRoot = pkgPath
 		}
 
-		err = fmtErrors(pkg.Errors)
+		loaded, err := load(modRoot, modDirs, overlay, options)
 		if err != nil {
 			return nil, err
 		}
+		pkgs = append(pkgs, loaded...)
+	}
+
+	goPkgs, errs := buildPackages(pkgs, overlay, options)
+	if err := errs[pkgPath]; err != nil {
+		return nil, err
+	}
+	if err := options.report(errs); err != nil {
+		return nil, err
+	}
 
-		goFiles := extractFile(pkg)
-		return &GoPackage{
-			pkgPath: pkg.PkgPath,
-			path:    path,
-			subPkgs: subPkgs,
-			Files:   goFiles,
-		}, nil
+	for _, goPkg := range packageTree(goPkgs) {
+		if goPkg.path == pkgPath {
+			return goPkg, nil
+		}
 	}
 
 	return nil, fmt.Errorf("package not found")
@@ -96,30 +179,132 @@ func (p *GoPackage) FileSet() *token.FileSet {
 	return p.fset
 }
 
+// Types return the type-checked package, nil if the package was loaded
+// without type information.
+func (p *GoPackage) Types() *types.Package {
+	return p.types
+}
+
+// TypesInfo return the type-checker information of the package files, nil if
+// the package was loaded without type information.
+func (p *GoPackage) TypesInfo() *types.Info {
+	return p.info
+}
+
+// AllFiles return the files of the package followed by its test files.
+func (p *GoPackage) AllFiles() []*GoFile {
+	return append(append([]*GoFile{}, p.Files...), p.TestFiles...)
+}
+
+// XTest return the external test package in the directory of the package, nil
+// if it doesn't exist or wasn't loaded with the WithXTest option.
+func (p *GoPackage) XTest() *GoPackage {
+	return p.xtest
+}
+
 // SubPkgs return all the subpackages.
 func (p *GoPackage) SubPkgs() []*GoPackage {
 	return p.subPkgs
 }
 
+// Move move the directory of the package, with its sub-packages, to the given
+// path. The path of the package, its files and its sub-packages are updated.
+// The import path is updated relatively to the previous one, the package must
+// stay in the same module. The destination must not exist.
+func (p *GoPackage) Move(dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	if root := moduleRoot(p.path); root == "" || moduleRoot(dir) != root {
+		return fmt.Errorf("%v is outside the module of the package", dir)
+	}
+
+	if _, err = os.Stat(dir); err == nil {
+		return fmt.Errorf("%v already exists", dir)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	rel, err := filepath.Rel(p.path, dir)
+	if err != nil {
+		return err
+	}
+	pkgPath := path.Join(p.pkgPath, filepath.ToSlash(rel))
+
+	err = os.MkdirAll(filepath.Dir(dir), 0755)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(p.path, dir)
+	if err != nil {
+		return err
+	}
+
+	p.relocate(p.path, dir, p.pkgPath, pkgPath)
+
+	return nil
+}
+
+// relocate replace the old directory and import path prefixes of the package,
+// its files and its sub-packages.
+func (p *GoPackage) relocate(oldDir, newDir, oldPkgPath, newPkgPath string) {
+	p.path = newDir + strings.TrimPrefix(p.path, oldDir)
+	p.pkgPath = newPkgPath + strings.TrimPrefix(p.pkgPath, oldPkgPath)
+
+	for _, file := range p.AllFiles() {
+		file.path = newDir + strings.TrimPrefix(file.path, oldDir)
+	}
+
+	if p.xtest != nil {
+		p.xtest.relocate(oldDir, newDir, oldPkgPath, newPkgPath)
+	}
+
+	for _, subPkg := range p.subPkgs {
+		subPkg.relocate(oldDir, newDir, oldPkgPath, newPkgPath)
+	}
+}
+
 // WritePkg method write the go file source code in the file at the given
 // path.
 func (p *GoPackage) WritePkg(path string, writeSubPkgs bool) error {
-	for _, file := range p.Files {
-		err := file.WriteFile(filepath.Join(path, file.Name()))
+	_, err := p.Write(path, writeSubPkgs, WriteOptions{})
+
+	return err
+}
+
+// Write atomically write the files of the package, including its test files
+// and its external test package, in the directory at the given path, and the
+// sub-packages in its sub-directories if recursive is true. Missing directories
+// are created. It return a WriteResult per file, the writing stops at the first
+// error.
+func (p *GoPackage) Write(path string, recursive bool, options WriteOptions) ([]WriteResult, error) {
+	files := p.AllFiles()
+	if p.xtest != nil {
+		files = append(files, p.xtest.AllFiles()...)
+	}
+
+	results := make([]WriteResult, 0, len(files))
+	for _, file := range files {
+		result, err := file.Write(filepath.Join(path, file.Name()), options)
 		if err != nil {
-			return err
+			return results, err
 		}
+
+		results = append(results, result)
 	}
 
-	if !writeSubPkgs {
-		return nil
+	if !recursive {
+		return results, nil
 	}
 	for _, subPkg := range p.subPkgs {
-		err := subPkg.WritePkg(filepath.Join(path, subPkg.Name()), true)
+		subResults, err := subPkg.Write(filepath.Join(path, subPkg.Name()), true, options)
+		results = append(results, subResults...)
 		if err != nil {
-			return err
+			return results, err
 		}
 	}
 
-	return nil
+	return results, nil
 }
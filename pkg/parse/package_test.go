@@ -1,6 +1,10 @@
 package parse
 
 import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
 	"path/filepath"
 	"testing"
 
@@ -56,3 +60,308 @@ func TestPkg_NewPkg_WithSubPkg(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, subPkg.Path(), subPkgPath)
 }
+
+func TestPkg_PackageFromOverlay(t *testing.T) {
+	dir := filepath.Join(".", "_data", "pkg", "virtual")
+
+	pkg, err := PackageFromOverlay(dir, map[string][]byte{
+		"greet.go": []byte(`package virtual
+
+import "github.com/negrel/asttk/pkg/parse/_data/pkg/virtual/log"
+
+func Greet(name string) {
+	log.Print("Hello " + name)
+}
+`),
+		"world.go": []byte(`package virtual
+
+func GreetWorld() {
+	Greet("World")
+}
+`),
+		filepath.Join("log", "log.go"): []byte(`package log
+
+func Print(msg string) {
+	println(msg)
+}
+`),
+	}, true)
+	assert.Nil(t, err, err)
+
+	assert.Equal(t, "github.com/negrel/asttk/pkg/parse/_data/pkg/virtual", pkg.PkgPath())
+	assert.Len(t, pkg.Files, 2)
+	assert.Len(t, pkg.SubPkgs(), 1)
+	assert.Equal(t, "log", pkg.SubPkgs()[0].Name())
+
+	bytes, err := pkg.Files[1].Bytes()
+	assert.Nil(t, err, err)
+	assert.Contains(t, string(bytes), "func GreetWorld() {")
+}
+
+func TestPkg_TypesInformation(t *testing.T) {
+	dir := filepath.Join(".", "_data", "pkg", "pkg_with_subpkg")
+
+	pkg, err := Package(dir, false)
+	assert.Nil(t, err, err)
+
+	assert.NotNil(t, pkg.Types())
+	assert.Equal(t, pkg.PkgPath(), pkg.Types().Path())
+	assert.NotNil(t, pkg.Types().Scope().Lookup("Greet"))
+
+	assert.NotNil(t, pkg.TypesInfo())
+	assert.NotEmpty(t, pkg.TypesInfo().Uses)
+
+	for _, file := range pkg.Files {
+		assert.Equal(t, pkg, file.Package())
+	}
+}
+
+func TestPkg_Move(t *testing.T) {
+	// The package must be part of the module to be loaded.
+	tmp, err := ioutil.TempDir(filepath.Join("_data", "pkg"), "move")
+	assert.Nil(t, err, err)
+	defer os.RemoveAll(tmp)
+
+	pkg, err := Package(filepath.Join("_data", "pkg", "pkg_with_subpkg"), true)
+	assert.Nil(t, err, err)
+	_, err = pkg.Write(filepath.Join(tmp, "greet"), true, WriteOptions{})
+	assert.Nil(t, err, err)
+
+	pkg, err = Package(filepath.Join(tmp, "greet"), true)
+	assert.Nil(t, err, err)
+	oldPkgPath := pkg.PkgPath()
+
+	// The destination already exists.
+	err = pkg.Move(tmp)
+	assert.NotNil(t, err)
+
+	// The destination is outside the module.
+	root, _ := filepath.Abs(filepath.Join("..", ".."))
+	err = pkg.Move(filepath.Join(filepath.Dir(root), "greet"))
+	assert.NotNil(t, err)
+
+	// The destination is in a nested module.
+	assert.Nil(t, os.MkdirAll(filepath.Join(tmp, "nested"), 0755))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(tmp, "nested", "go.mod"), []byte("module nested\n"), 0644))
+	err = pkg.Move(filepath.Join(tmp, "nested", "greet"))
+	assert.NotNil(t, err)
+	assert.Equal(t, oldPkgPath, pkg.PkgPath())
+
+	dir, _ := filepath.Abs(filepath.Join(tmp, "hello", "world"))
+	err = pkg.Move(dir)
+	assert.Nil(t, err, err)
+
+	assert.Equal(t, dir, pkg.Path())
+	assert.Equal(t, path.Dir(oldPkgPath)+"/hello/world", pkg.PkgPath())
+	assert.Equal(t, filepath.Join(dir, "greet.go"), pkg.Files[0].Path())
+
+	subPkg := pkg.SubPkgs()[0]
+	assert.Equal(t, filepath.Join(dir, "log"), subPkg.Path())
+	assert.Equal(t, pkg.PkgPath()+"/log", subPkg.PkgPath())
+	assert.Equal(t, filepath.Join(dir, "log", "log.go"), subPkg.Files[0].Path())
+
+	_, err = os.Stat(subPkg.Files[0].Path())
+	assert.Nil(t, err, err)
+	_, err = os.Stat(filepath.Join(tmp, "greet"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPkg_Tests(t *testing.T) {
+	dir := filepath.Join(".", "_data", "pkg", "tested")
+
+	pkg, err := Package(dir, false)
+	assert.Nil(t, err, err)
+	assert.Len(t, pkg.Files, 1)
+	assert.Empty(t, pkg.TestFiles)
+	assert.Nil(t, pkg.XTest())
+
+	pkg, err = Package(dir, false, WithTests())
+	assert.Nil(t, err, err)
+	assert.Len(t, pkg.Files, 1)
+	assert.Len(t, pkg.TestFiles, 1)
+	assert.Len(t, pkg.AllFiles(), 2)
+	assert.Equal(t, "greet_test.go", pkg.TestFiles[0].Name())
+	assert.Equal(t, pkg, pkg.TestFiles[0].Package())
+	assert.Equal(t, "github.com/negrel/asttk/pkg/parse/_data/pkg/tested", pkg.PkgPath())
+	assert.NotNil(t, pkg.Types().Scope().Lookup("TestGreet"))
+	assert.Nil(t, pkg.XTest())
+
+	pkg, err = Package(dir, false, WithTests(), WithXTest())
+	assert.Nil(t, err, err)
+	assert.Len(t, pkg.TestFiles, 1)
+
+	xtest := pkg.XTest()
+	assert.NotNil(t, xtest)
+	assert.Equal(t, pkg.PkgPath()+"_test", xtest.PkgPath())
+	assert.Len(t, xtest.Files, 1)
+	assert.Equal(t, "example_test.go", xtest.Files[0].Name())
+	assert.NotNil(t, xtest.TypesInfo())
+}
+
+func TestPkg_LoadOptions(t *testing.T) {
+	dir := filepath.Join(".", "_data", "pkg", "tagged")
+	fileNames := func(pkg *GoPackage) []string {
+		var names []string
+		for _, file := range pkg.Files {
+			names = append(names, file.Name())
+		}
+
+		return names
+	}
+
+	pkg, err := Package(dir, false, WithEnv("GOOS=linux"))
+	assert.Nil(t, err, err)
+	assert.Equal(t, []string{"plain.go"}, fileNames(pkg))
+
+	pkg, err = Package(dir, false, WithEnv("GOOS=linux"), WithBuildTags("custom"))
+	assert.Nil(t, err, err)
+	assert.Equal(t, []string{"custom.go", "plain.go"}, fileNames(pkg))
+	assert.NotNil(t, pkg.Types().Scope().Lookup("Custom"))
+
+	pkg, err = Package(dir, false, WithEnv("GOOS=windows"))
+	assert.Nil(t, err, err)
+	assert.Equal(t, []string{"plain.go", "tagged_windows.go"}, fileNames(pkg))
+
+	// The global config is left untouched.
+	assert.Empty(t, Config.BuildFlags)
+	assert.Nil(t, Config.Env)
+}
+
+func TestPkg_SyntaxOnly(t *testing.T) {
+	dir := filepath.Join(".", "_data", "pkg", "pkg_with_subpkg")
+
+	pkg, err := Package(dir, true, WithSyntaxOnly())
+	assert.Nil(t, err, err)
+
+	assert.Nil(t, pkg.Types())
+	assert.Nil(t, pkg.TypesInfo())
+	assert.Len(t, pkg.Files, 1)
+	assert.Equal(t, "pkg_with_subpkg", pkg.Files[0].AST().Name.Name)
+	assert.NotNil(t, pkg.FileSet())
+	assert.Len(t, pkg.SubPkgs(), 1)
+	assert.Nil(t, pkg.SubPkgs()[0].Types())
+}
+
+func TestPkg_Context(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := Package(filepath.Join(".", "_data", "pkg", "pkg_with_subpkg"), false, WithContext(ctx))
+	assert.NotNil(t, err)
+}
+
+func TestLoad(t *testing.T) {
+	pkgs, err := Load([]string{"./_data/pkg/..."})
+	assert.Nil(t, err, err)
+
+	var names []string
+	for _, pkg := range pkgs {
+		names = append(names, pkg.Name())
+	}
+	assert.Equal(t, []string{"pkg_with_subpkg", "tagged", "tested"}, names)
+
+	expected, err := Package(filepath.Join(".", "_data", "pkg", "pkg_with_subpkg"), true)
+	assert.Nil(t, err, err)
+	assert.Equal(t, expected.PkgPath(), pkgs[0].PkgPath())
+	assert.Len(t, pkgs[0].SubPkgs(), 1)
+	assert.Equal(t, expected.SubPkgs()[0].PkgPath(), pkgs[0].SubPkgs()[0].PkgPath())
+	assert.NotNil(t, pkgs[0].SubPkgs()[0].Types())
+
+	pkgs, err = Load([]string{"github.com/negrel/asttk/pkg/parse/_data/pkg/tested"}, WithTests(), WithXTest())
+	assert.Nil(t, err, err)
+	assert.Len(t, pkgs, 1)
+	assert.Len(t, pkgs[0].TestFiles, 1)
+	assert.NotNil(t, pkgs[0].XTest())
+
+	pkgs, err = Load([]string{"file=log/log.go", "."}, WithDir(filepath.Join("_data", "pkg", "pkg_with_subpkg")))
+	assert.Nil(t, err, err)
+	assert.Len(t, pkgs, 1)
+	assert.Equal(t, "pkg_with_subpkg", pkgs[0].Name())
+	assert.Equal(t, "log", pkgs[0].SubPkgs()[0].Name())
+
+	_, err = Load([]string{"./_data/pkg/missing/..."})
+	assert.NotNil(t, err)
+
+	_, err = Load(nil)
+	assert.NotNil(t, err)
+}
+
+func TestPkg_SubPkgsDirs(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join(".", "_data", "subpkgs"))
+	assert.Nil(t, err, err)
+
+	result := &LoadResult{}
+	pkg, err := Package(dir, true, WithResult(result))
+	assert.Nil(t, err, err)
+	assert.Empty(t, result.Skipped)
+
+	// testdata, _ignored and .hidden are ignored.
+	subPkgs := pkg.SubPkgs()
+	assert.Len(t, subPkgs, 2)
+
+	// The nested module is loaded from its own module.
+	assert.Equal(t, filepath.Join(dir, "module"), subPkgs[0].Path())
+	assert.Equal(t, "example.com/module", subPkgs[0].PkgPath())
+	assert.NotNil(t, subPkgs[0].Types())
+	assert.Len(t, subPkgs[0].SubPkgs(), 1)
+	assert.Equal(t, "example.com/module/inner", subPkgs[0].SubPkgs()[0].PkgPath())
+
+	assert.Equal(t, filepath.Join(dir, "plain"), subPkgs[1].Path())
+
+	// ParseDir find the same packages.
+	parsed, err := ParseDir(dir, true)
+	assert.Nil(t, err, err)
+	assert.Len(t, parsed.SubPkgs(), 2)
+	assert.Equal(t, "example.com/module", parsed.SubPkgs()[0].PkgPath())
+}
+
+func TestPkg_SkippedSubPkgs(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join(".", "_data", "broken"))
+	assert.Nil(t, err, err)
+
+	result := &LoadResult{}
+	pkg, err := Package(dir, true, WithResult(result))
+	assert.Nil(t, err, err)
+
+	// Packages nested in directories without go files are kept.
+	assert.Len(t, pkg.SubPkgs(), 1)
+	assert.Equal(t, filepath.Join(dir, "nested", "inner"), pkg.SubPkgs()[0].Path())
+
+	kinds := make(map[string]ErrorKind)
+	for _, skipped := range result.Skipped {
+		assert.NotEmpty(t, skipped.Errors)
+		kinds[filepath.Base(skipped.Dir)] = skipped.Errors[0].Kind
+	}
+	assert.Equal(t, map[string]ErrorKind{
+		"syntax":   ParseError,
+		"tagged":   BuildConstraintsError,
+		"testonly": NoGoFilesError,
+		"typed":    TypeError,
+	}, kinds)
+
+	syntaxErr := result.Skipped[0].Errors[0]
+	assert.Equal(t, filepath.Join(dir, "syntax", "syntax.go"), syntaxErr.Pos.Filename)
+	assert.Equal(t, 3, syntaxErr.Pos.Line)
+
+	_, err = Package(dir, true, WithStrict())
+	assert.NotNil(t, err)
+	pkgErr, isPkgErr := err.(*PackageError)
+	assert.True(t, isPkgErr)
+	assert.Equal(t, filepath.Join(dir, "syntax"), pkgErr.Dir)
+
+	_, err = Package(filepath.Join(dir, "typed"), false)
+	assert.NotNil(t, err)
+	pkgErr, isPkgErr = err.(*PackageError)
+	assert.True(t, isPkgErr)
+	assert.Equal(t, TypeError, pkgErr.Errors[0].Kind)
+	assert.Contains(t, pkgErr.Error(), "typed.go:3:")
+
+	pkgs, err := Load([]string{"./_data/broken/...", "./_data/broken/tagged"}, WithResult(result))
+	assert.Nil(t, err, err)
+	assert.Len(t, pkgs, 1)
+	assert.Len(t, result.Skipped, 4)
+	assert.Equal(t, BuildConstraintsError, result.Skipped[1].Errors[0].Kind)
+
+	_, err = Load([]string{"./_data/broken/..."}, WithStrict())
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,300 @@
+package parse
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// ParseFile parse the file at the given path with go/parser, without running
+// the go command nor type-checking the file. It works outside a module. The
+// returned file belongs to a GoPackage that contains only this file and has no
+// type information.
+//
+// The WithFileSet and WithContext options are supported.
+func ParseFile(filePath string, opts ...Option) (*GoFile, error) {
+	options := newLoadOptions(opts)
+
+	filePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Ext(filePath) != ".go" {
+		return nil, fmt.Errorf("the given file path should end with a \".go\" extension")
+	}
+
+	if options.ctx != nil && options.ctx.Err() != nil {
+		return nil, options.ctx.Err()
+	}
+
+	dir := filepath.Dir(filePath)
+	goPkg := &GoPackage{
+		pkgPath: modulePkgPath(dir),
+		path:    dir,
+		subPkgs: []*GoPackage{},
+		fset:    options.fileSet(),
+	}
+
+	file, err := parseFile(goPkg, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasSuffix(filePath, "_test.go") {
+		goPkg.TestFiles = []*GoFile{file}
+	} else {
+		goPkg.Files = []*GoFile{file}
+	}
+
+	return file, nil
+}
+
+// ParseDir parse the package in the given directory, and its sub-packages if
+// parseSubPkgs is true, with go/parser. It is much faster than Package as it
+// doesn't run the go command nor type-check the packages, and it works outside
+// a module. The files of the returned packages share a single FileSet, the
+// packages have no type information.
+//
+// Files are selected using the build constraints of go/build. The WithTests,
+// WithXTest, WithBuildTags, WithEnv (GOOS, GOARCH and CGO_ENABLED only),
+// WithFileSet, WithContext, WithStrict and WithResult options are supported.
+// Like the go command, sub-directories named testdata or starting with "_" or
+// "." are ignored.
+func ParseDir(dir string, parseSubPkgs bool, opts ...Option) (*GoPackage, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("the given path is empty")
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	options := newLoadOptions(opts)
+	options.fset = options.fileSet()
+	buildCtx := options.buildContext()
+
+	goPkg, subDirs, err := parseDir(dir, options, buildCtx)
+	if err != nil {
+		return nil, err
+	}
+	if goPkg == nil {
+		return nil, &PackageError{Dir: dir, Errors: []Error{{
+			Kind: NoGoFilesError,
+			Msg:  fmt.Sprintf("no go files in %v", dir),
+		}}}
+	}
+	if !parseSubPkgs {
+		return goPkg, nil
+	}
+
+	goPkgs := []*GoPackage{goPkg}
+	errs := make(map[string]*PackageError)
+	for len(subDirs) > 0 {
+		subDir := subDirs[0]
+		subPkg, dirs, err := parseDir(subDir, options, buildCtx)
+		subDirs = append(subDirs[1:], dirs...)
+
+		if options.ctx != nil && options.ctx.Err() != nil {
+			return nil, options.ctx.Err()
+		}
+
+		if pkgErr, isPkgErr := err.(*PackageError); isPkgErr {
+			errs[subDir] = pkgErr
+		} else if err != nil {
+			errs[subDir] = &PackageError{Dir: subDir, Errors: []Error{{Kind: UnknownError, Msg: err.Error()}}}
+		} else if subPkg != nil {
+			goPkgs = append(goPkgs, subPkg)
+		}
+	}
+
+	if err := options.report(errs); err != nil {
+		return nil, err
+	}
+	packageTree(goPkgs)
+
+	return goPkg, nil
+}
+
+// parseDir parse the package in the given directory and return its
+// sub-directories. The package is nil if the directory doesn't contain any go
+// file. A *PackageError is returned if the package can't be parsed.
+func parseDir(dir string, options loadOptions, buildCtx build.Context) (*GoPackage, []string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	goPkg := &GoPackage{
+		pkgPath: modulePkgPath(dir),
+		path:    dir,
+		subPkgs: []*GoPackage{},
+		fset:    options.fset,
+	}
+	xtest := &GoPackage{
+		pkgPath: goPkg.pkgPath,
+		path:    dir,
+		subPkgs: []*GoPackage{},
+		fset:    options.fset,
+	}
+
+	var subDirs []string
+	hasGoFiles, excluded := false, false
+	for _, entry := range entries {
+		name := entry.Name()
+
+		if entry.IsDir() {
+			if !ignoredDir(name) {
+				subDirs = append(subDirs, filepath.Join(dir, name))
+			}
+			continue
+		}
+
+		if filepath.Ext(name) != ".go" {
+			continue
+		}
+		hasGoFiles = true
+
+		isTest := strings.HasSuffix(name, "_test.go")
+		if isTest && !options.tests && !options.xtest {
+			continue
+		}
+
+		match, err := buildCtx.MatchFile(dir, name)
+		if err != nil {
+			return nil, subDirs, newParseError(dir, err)
+		}
+		if !match {
+			excluded = excluded || !isTest
+			continue
+		}
+
+		if options.ctx != nil && options.ctx.Err() != nil {
+			return nil, subDirs, options.ctx.Err()
+		}
+
+		file, err := parseFile(goPkg, filepath.Join(dir, name))
+		if err != nil {
+			return nil, subDirs, newParseError(dir, err)
+		}
+
+		switch {
+		case !isTest:
+			goPkg.Files = append(goPkg.Files, file)
+		case strings.HasSuffix(file.ast.Name.Name, "_test"):
+			if options.xtest {
+				file.pkg = xtest
+				xtest.Files = append(xtest.Files, file)
+			}
+		case options.tests:
+			goPkg.TestFiles = append(goPkg.TestFiles, file)
+		}
+	}
+
+	if !hasGoFiles {
+		return nil, subDirs, nil
+	}
+	if len(goPkg.Files) == 0 && excluded {
+		return nil, subDirs, &PackageError{Dir: dir, Errors: []Error{{
+			Kind: BuildConstraintsError,
+			Msg:  fmt.Sprintf("build constraints exclude all go files in %v", dir),
+		}}}
+	}
+	if len(goPkg.Files) == 0 {
+		return nil, subDirs, &PackageError{Dir: dir, Errors: []Error{{
+			Kind: NoGoFilesError,
+			Msg:  fmt.Sprintf("no non-test go files in %v", dir),
+		}}}
+	}
+
+	if len(xtest.Files) > 0 {
+		if xtest.pkgPath != "" {
+			xtest.pkgPath += "_test"
+		}
+		goPkg.xtest = xtest
+	}
+
+	return goPkg, subDirs, nil
+}
+
+func parseFile(goPkg *GoPackage, filePath string) (*GoFile, error) {
+	src, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	astFile, err := parser.ParseFile(goPkg.fset, filePath, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GoFile{
+		path: filePath,
+		ast:  astFile,
+		fset: goPkg.fset,
+		pkg:  goPkg,
+		src:  src,
+
+		comments: ast.NewCommentMap(goPkg.fset, astFile, astFile.Comments),
+		loaded:   commentSet(astFile.Comments),
+	}, nil
+}
+
+// buildContext return the go/build context matching the options.
+func (options loadOptions) buildContext() build.Context {
+	buildCtx := build.Default
+	buildCtx.BuildTags = options.buildTags
+
+	for _, variable := range options.env {
+		key, value, _ := strings.Cut(variable, "=")
+
+		switch key {
+		case "GOOS":
+			buildCtx.GOOS = value
+		case "GOARCH":
+			buildCtx.GOARCH = value
+		case "CGO_ENABLED":
+			buildCtx.CgoEnabled = value == "1"
+		}
+	}
+
+	return buildCtx
+}
+
+// modulePkgPath return the import path of the package in the given directory
+// according to the closest go.mod file, or an empty string if the directory
+// isn't part of a module.
+func modulePkgPath(dir string) string {
+	for modDir := dir; ; {
+		data, err := ioutil.ReadFile(filepath.Join(modDir, "go.mod"))
+		if err == nil {
+			modPath := modfile.ModulePath(data)
+			if modPath == "" {
+				return ""
+			}
+
+			rel, err := filepath.Rel(modDir, dir)
+			if err != nil {
+				return ""
+			}
+
+			return path.Join(modPath, filepath.ToSlash(rel))
+		} else if !os.IsNotExist(err) {
+			return ""
+		}
+
+		parent := filepath.Dir(modDir)
+		if parent == modDir {
+			return ""
+		}
+		modDir = parent
+	}
+}
@@ -0,0 +1,135 @@
+package parse
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDir(t *testing.T) {
+	dir := filepath.Join(".", "_data", "pkg", "pkg_with_subpkg")
+
+	expected, err := Package(dir, true)
+	assert.Nil(t, err, err)
+
+	pkg, err := ParseDir(dir, true)
+	assert.Nil(t, err, err)
+
+	assert.Equal(t, expected.Path(), pkg.Path())
+	assert.Equal(t, expected.PkgPath(), pkg.PkgPath())
+	assert.Nil(t, pkg.Types())
+	assert.Nil(t, pkg.TypesInfo())
+	assert.Len(t, pkg.Files, 1)
+	assert.Equal(t, pkg, pkg.Files[0].Package())
+
+	assert.Len(t, pkg.SubPkgs(), 1)
+	subPkg := pkg.SubPkgs()[0]
+	assert.Equal(t, expected.SubPkgs()[0].PkgPath(), subPkg.PkgPath())
+	assert.Equal(t, pkg.FileSet(), subPkg.FileSet())
+	assert.Equal(t, pkg.FileSet(), subPkg.Files[0].FileSet())
+
+	expectedSrc, err := expected.Files[0].Bytes()
+	assert.Nil(t, err, err)
+	src, err := pkg.Files[0].Bytes()
+	assert.Nil(t, err, err)
+	assert.Equal(t, string(expectedSrc), string(src))
+
+	_, err = ParseDir(filepath.Join(".", "_data", "file", "missing"), false)
+	assert.NotNil(t, err)
+}
+
+func TestParseDir_Options(t *testing.T) {
+	dir := filepath.Join(".", "_data", "pkg", "tagged")
+	fileNames := func(pkg *GoPackage) []string {
+		var names []string
+		for _, file := range pkg.Files {
+			names = append(names, file.Name())
+		}
+
+		return names
+	}
+
+	pkg, err := ParseDir(dir, false, WithEnv("GOOS=linux"))
+	assert.Nil(t, err, err)
+	assert.Equal(t, []string{"plain.go"}, fileNames(pkg))
+
+	pkg, err = ParseDir(dir, false, WithEnv("GOOS=windows"), WithBuildTags("custom"))
+	assert.Nil(t, err, err)
+	assert.Equal(t, []string{"custom.go", "plain.go", "tagged_windows.go"}, fileNames(pkg))
+
+	dir = filepath.Join(".", "_data", "pkg", "tested")
+
+	pkg, err = ParseDir(dir, false)
+	assert.Nil(t, err, err)
+	assert.Empty(t, pkg.TestFiles)
+	assert.Nil(t, pkg.XTest())
+
+	pkg, err = ParseDir(dir, false, WithTests(), WithXTest())
+	assert.Nil(t, err, err)
+	assert.Len(t, pkg.TestFiles, 1)
+	assert.NotNil(t, pkg.XTest())
+	assert.Equal(t, pkg.PkgPath()+"_test", pkg.XTest().PkgPath())
+	assert.Equal(t, "example_test.go", pkg.XTest().Files[0].Name())
+	assert.Equal(t, pkg.XTest(), pkg.XTest().Files[0].Package())
+}
+
+func TestParseFile_OutsideModule(t *testing.T) {
+	dir := t.TempDir()
+	src := "package main\n\n// main doc.\nfunc main() {\n}\n"
+	err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(src), 0644)
+	assert.Nil(t, err, err)
+
+	file, err := ParseFile(filepath.Join(dir, "main.go"))
+	assert.Nil(t, err, err)
+	assert.Equal(t, "", file.Package().PkgPath())
+	assert.Equal(t, []*GoFile{file}, file.Package().Files)
+
+	bytes, err := file.Bytes()
+	assert.Nil(t, err, err)
+	assert.Equal(t, src, string(bytes))
+
+	// The FileSet is shared with the package parsed next.
+	pkg, err := ParseDir(dir, true, WithFileSet(file.FileSet()))
+	assert.Nil(t, err, err)
+	assert.Equal(t, file.FileSet(), pkg.FileSet())
+	assert.Equal(t, "main", pkg.Files[0].AST().Name.Name)
+
+	_, err = ParseFile(filepath.Join(dir, "README.md"))
+	assert.NotNil(t, err)
+}
+
+func TestParseDir_Skipped(t *testing.T) {
+	dir, err := filepath.Abs(filepath.Join(".", "_data", "broken"))
+	assert.Nil(t, err, err)
+
+	result := &LoadResult{}
+	pkg, err := ParseDir(dir, true, WithResult(result))
+	assert.Nil(t, err, err)
+
+	// Type errors aren't detected without type-checking.
+	var names []string
+	for _, subPkg := range pkg.SubPkgs() {
+		names = append(names, subPkg.Name())
+	}
+	assert.Equal(t, []string{"inner", "typed"}, names)
+
+	kinds := make(map[string]ErrorKind)
+	for _, skipped := range result.Skipped {
+		kinds[filepath.Base(skipped.Dir)] = skipped.Errors[0].Kind
+	}
+	assert.Equal(t, map[string]ErrorKind{
+		"syntax":   ParseError,
+		"tagged":   BuildConstraintsError,
+		"testonly": NoGoFilesError,
+	}, kinds)
+
+	_, err = ParseDir(dir, true, WithStrict())
+	assert.NotNil(t, err)
+
+	_, err = ParseDir(filepath.Join(dir, "nested"), false)
+	pkgErr, isPkgErr := err.(*PackageError)
+	assert.True(t, isPkgErr)
+	assert.Equal(t, NoGoFilesError, pkgErr.Errors[0].Kind)
+}
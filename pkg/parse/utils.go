@@ -2,58 +2,337 @@ package parse
 
 import (
 	"fmt"
+	"go/ast"
 	"io/ioutil"
+	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 
 	"golang.org/x/tools/go/packages"
 )
 
-func findSubPkgs(dir string) (subPkgs []*GoPackage) {
-	filesInfo, err := ioutil.ReadDir(dir)
-	if err != nil {
-		return
+// load the packages matching the given patterns, the packages in the given
+// directories for example. The go/packages driver is run from the closest
+// existing directory so packages made of virtual files can be loaded.
+func load(dir string, patterns []string, overlay map[string][]byte, options loadOptions) ([]*packages.Package, error) {
+	config := options.config()
+	config.Dir = existingDir(dir)
+	config.Overlay = overlay
+
+	return packages.Load(&config, patterns...)
+}
+
+// packageDirs return the given directory and its sub-directories containing go
+// files, on disk or in the overlay, by module root. The directories outside a
+// module are returned with an empty module root. Like ParseDir, the
+// sub-directories named testdata or starting with "_" or "." are ignored.
+func packageDirs(root string, overlay map[string][]byte) (map[string][]string, error) {
+	found := map[string]bool{root: true}
+
+	queue := []string{root}
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+
+		entries, err := ioutil.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			name := entry.Name()
+
+			if entry.IsDir() && !ignoredDir(name) {
+				queue = append(queue, filepath.Join(dir, name))
+			} else if !entry.IsDir() && filepath.Ext(name) == ".go" {
+				found[dir] = true
+			}
+		}
 	}
 
-	for _, fileInfo := range filesInfo {
-		if !fileInfo.IsDir() {
+	for path := range overlay {
+		dir := filepath.Dir(path)
+		rel, err := filepath.Rel(root, dir)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
 			continue
 		}
 
-		filePath := filepath.Join(dir, fileInfo.Name())
-		subPkg, err := Package(filePath, true)
-		if err != nil {
+		ignored := false
+		for _, name := range strings.Split(rel, string(filepath.Separator)) {
+			ignored = ignored || (rel != "." && ignoredDir(name))
+		}
+		if !ignored && filepath.Ext(path) == ".go" {
+			found[dir] = true
+		}
+	}
+
+	dirs := make(map[string][]string)
+	for dir := range found {
+		modRoot := moduleRoot(dir)
+		dirs[modRoot] = append(dirs[modRoot], dir)
+	}
+	for _, modDirs := range dirs {
+		sort.Strings(modDirs)
+	}
+
+	return dirs, nil
+}
+
+// ignoredDir return true if the go command ignores the directories with the
+// given name when it matches "..." patterns.
+func ignoredDir(name string) bool {
+	return name == "testdata" || strings.HasPrefix(name, "_") || strings.HasPrefix(name, ".")
+}
+
+// moduleRoot return the closest directory containing a go.mod file among the
+// given directory and its parents, or an empty string.
+func moduleRoot(dir string) string {
+	for dir = existingDir(dir); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir
+		}
+
+		if filepath.Dir(dir) == dir {
+			return ""
+		}
+	}
+}
+
+func existingDir(dir string) string {
+	for {
+		if fileInfo, err := os.Stat(dir); err == nil && fileInfo.IsDir() {
+			return dir
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// overlayHasDir return true if the given overlay contains a file in the
+// given directory or in one of its sub-directories.
+func overlayHasDir(overlay map[string][]byte, dir string) bool {
+	for path := range overlay {
+		if strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// selectPackages return the package in the given directory, its test variant
+// and its external test package among the given loaded packages.
+func selectPackages(pkgs []*packages.Package, dir string) (pkg, testPkg, xtest *packages.Package) {
+	for _, p := range pkgs {
+		if len(p.GoFiles) == 0 || filepath.Dir(p.GoFiles[0]) != dir {
 			continue
 		}
 
-		subPkgs = append(subPkgs, subPkg)
+		// The test variants of the packages imported by other tests, with
+		// an ID like "pkg [other.test]", are ignored.
+		switch p.ID {
+		case p.PkgPath:
+			pkg = p
+		case p.PkgPath + " [" + p.PkgPath + ".test]":
+			testPkg = p
+		case p.PkgPath + " [" + strings.TrimSuffix(p.PkgPath, "_test") + ".test]":
+			xtest = p
+		}
 	}
 
 	return
 }
 
-func fmtErrors(errors []packages.Error) error {
-	if length := len(errors); length != 0 {
-		errors := fmt.Sprintf("%v error(s) found:\n", length)
+// buildPackage build the GoPackage in the given directory from the loaded
+// packages. It return the loaded packages used so their errors can be checked.
+func buildPackage(pkgs []*packages.Package, dir string, subPkgs []*GoPackage, overlay map[string][]byte, options loadOptions) (*GoPackage, []*packages.Package) {
+	pkg, testPkg, xtest := selectPackages(pkgs, dir)
+	if options.tests && testPkg != nil {
+		pkg = testPkg
+	}
+	if pkg == nil {
+		return nil, nil
+	}
+
+	goPkg := newPackage(pkg, subPkgs, overlay)
+	used := []*packages.Package{pkg}
 
-		for _, err := range errors {
-			errors += fmt.Sprint(err) + "\n"
+	if options.xtest && xtest != nil {
+		goPkg.xtest = newPackage(xtest, nil, overlay)
+		// The files of an external test package are all test files, they
+		// are its regular files.
+		goPkg.xtest.Files, goPkg.xtest.TestFiles = goPkg.xtest.AllFiles(), nil
+		used = append(used, xtest)
+	}
+
+	return goPkg, used
+}
+
+// buildPackages build the GoPackage of every directory of the given loaded
+// packages. The loaded packages with errors are skipped, their errors are
+// returned by directory.
+func buildPackages(pkgs []*packages.Package, overlay map[string][]byte, options loadOptions) ([]*GoPackage, map[string]*PackageError) {
+	byDir := make(map[string][]*packages.Package)
+	var dirs []string
+	for _, pkg := range pkgs {
+		// The generated test main packages have no directory of their own.
+		if strings.HasSuffix(pkg.ID, ".test") {
+			continue
+		}
+
+		// The packages not found have no directory, their ID is the
+		// pattern that matched them.
+		dir := packageDir(pkg)
+		if dir == "" {
+			dir = pkg.ID
+		}
+
+		if _, ok := byDir[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		byDir[dir] = append(byDir[dir], pkg)
+	}
+	sort.Strings(dirs)
+
+	var goPkgs []*GoPackage
+	errs := make(map[string]*PackageError)
+	for _, dir := range dirs {
+		goPkg, used := buildPackage(byDir[dir], dir, []*GoPackage{}, overlay, options)
+		if goPkg == nil {
+			used = byDir[dir]
+		}
+
+		var pkgErrors []packages.Error
+		for _, pkg := range used {
+			pkgErrors = append(pkgErrors, pkg.Errors...)
+		}
+		if err := newPackageError(dir, pkgErrors); err != nil {
+			errs[dir] = err
+			continue
+		}
+
+		// The directory only contains test files.
+		if goPkg == nil {
+			errs[dir] = &PackageError{Dir: dir, Errors: []Error{{
+				Kind: NoGoFilesError,
+				Msg:  fmt.Sprintf("no non-test go files in %v", dir),
+			}}}
+			continue
+		}
+
+		goPkgs = append(goPkgs, goPkg)
+	}
+
+	return goPkgs, errs
+}
+
+// packageDir return the directory of the given loaded package.
+func packageDir(pkg *packages.Package) string {
+	if len(pkg.GoFiles) > 0 {
+		return filepath.Dir(pkg.GoFiles[0])
+	}
+
+	return pkg.Dir
+}
+
+// packageTree add every given package to the sub-packages of the closest
+// package containing its directory, and return the packages without parent.
+// The packages are sorted by path.
+func packageTree(goPkgs []*GoPackage) []*GoPackage {
+	sort.Slice(goPkgs, func(i, j int) bool {
+		return goPkgs[i].path < goPkgs[j].path
+	})
+
+	byDir := make(map[string]*GoPackage, len(goPkgs))
+	var roots []*GoPackage
+	for _, goPkg := range goPkgs {
+		byDir[goPkg.path] = goPkg
+
+		parent := (*GoPackage)(nil)
+		for dir := goPkg.path; parent == nil && filepath.Dir(dir) != dir; {
+			dir = filepath.Dir(dir)
+			parent = byDir[dir]
+		}
+
+		if parent != nil {
+			parent.subPkgs = append(parent.subPkgs, goPkg)
+		} else {
+			roots = append(roots, goPkg)
 		}
+	}
+
+	return roots
+}
 
-		return fmt.Errorf(errors)
+func newPackage(pkg *packages.Package, subPkgs []*GoPackage, overlay map[string][]byte) *GoPackage {
+	goPkg := &GoPackage{
+		pkgPath: pkg.PkgPath,
+		path:    filepath.Dir(pkg.GoFiles[0]),
+		subPkgs: subPkgs,
+		fset:    pkg.Fset,
+		types:   pkg.Types,
+		info:    pkg.TypesInfo,
+	}
+
+	for _, file := range extractFile(pkg, goPkg, overlay) {
+		if strings.HasSuffix(file.path, "_test.go") {
+			goPkg.TestFiles = append(goPkg.TestFiles, file)
+		} else {
+			goPkg.Files = append(goPkg.Files, file)
+		}
 	}
 
-	return nil
+	return goPkg
 }
 
-func extractFile(pkg *packages.Package) []*GoFile {
+func extractFile(pkg *packages.Package, goPkg *GoPackage, overlay map[string][]byte) []*GoFile {
 	goFiles := make([]*GoFile, len(pkg.Syntax))
 	for i := 0; i < len(goFiles); i++ {
 		goFiles[i] = &GoFile{
 			path: pkg.GoFiles[i],
 			ast:  pkg.Syntax[i],
 			fset: pkg.Fset,
+			pkg:  goPkg,
+			src:  readSource(pkg.GoFiles[i], overlay),
+
+			comments: ast.NewCommentMap(pkg.Fset, pkg.Syntax[i], pkg.Syntax[i].Comments),
+			loaded:   commentSet(pkg.Syntax[i].Comments),
 		}
 	}
 
 	return goFiles
 }
+
+// commentSet return the given comment groups as a set.
+func commentSet(groups []*ast.CommentGroup) map[*ast.CommentGroup]bool {
+	set := make(map[*ast.CommentGroup]bool, len(groups))
+	for _, group := range groups {
+		set[group] = true
+	}
+
+	return set
+}
+
+// readSource return the source code of the file at the given path, the overlay
+// content takes precedence over the file on disk. It return nil if the file
+// can't be read.
+func readSource(path string, overlay map[string][]byte) []byte {
+	if src, ok := overlay[path]; ok {
+		return src
+	}
+
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	return src
+}
@@ -0,0 +1,72 @@
+package parse
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// WriteOptions configure how GoFile and GoPackage are written.
+type WriteOptions struct {
+	// DryRun compute the content of the files without writing them.
+	DryRun bool
+}
+
+// WriteResult describe a file written by GoFile.Write or GoPackage.Write.
+type WriteResult struct {
+	// Path is the path of the written file.
+	Path string
+	// Content is the new content of the file.
+	Content []byte
+	// Changed is true if the file didn't exist or its content differed.
+	Changed bool
+}
+
+// writeFile atomically replace the content of the file at the given path by
+// writing it to a temporary file that is renamed afterward. Existing files
+// keep their permissions, new files and missing directories are created.
+func writeFile(path string, content []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	if fileInfo, err := os.Stat(path); err == nil {
+		mode = fileInfo.Mode().Perm()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// changed return true if the file at the given path doesn't exist or its
+// content differs from the given one.
+func changed(path string, content []byte) bool {
+	current, err := ioutil.ReadFile(path)
+	if err != nil {
+		return true
+	}
+
+	return !bytes.Equal(current, content)
+}
@@ -0,0 +1,89 @@
+package parse
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFile_Write(t *testing.T) {
+	src := "package main\n\nfunc main() {\n}\n"
+	goFile, err := FileFromSource(filepath.Join("_data", "virtual", "main.go"), []byte(src))
+	assert.Nil(t, err, err)
+
+	// The existing file is longer than the new content.
+	path := filepath.Join(t.TempDir(), "main.go")
+	err = ioutil.WriteFile(path, []byte(src+"\n// trailing comment\n"), 0600)
+	assert.Nil(t, err, err)
+
+	// Dry run.
+	result, err := goFile.Write(path, WriteOptions{DryRun: true})
+	assert.Nil(t, err, err)
+	assert.True(t, result.Changed)
+	assert.Equal(t, src, string(result.Content))
+
+	content, err := ioutil.ReadFile(path)
+	assert.Nil(t, err, err)
+	assert.NotEqual(t, src, string(content))
+
+	// Write.
+	result, err = goFile.Write(path, WriteOptions{})
+	assert.Nil(t, err, err)
+	assert.True(t, result.Changed)
+
+	content, err = ioutil.ReadFile(path)
+	assert.Nil(t, err, err)
+	assert.Equal(t, src, string(content))
+
+	fileInfo, err := os.Stat(path)
+	assert.Nil(t, err, err)
+	assert.Equal(t, os.FileMode(0600), fileInfo.Mode().Perm())
+
+	// Up to date.
+	result, err = goFile.Write(path, WriteOptions{})
+	assert.Nil(t, err, err)
+	assert.False(t, result.Changed)
+
+	// No temporary file left.
+	entries, err := ioutil.ReadDir(filepath.Dir(path))
+	assert.Nil(t, err, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestPkg_Write(t *testing.T) {
+	pkgPath := filepath.Join(".", "_data", "pkg", "pkg_with_subpkg")
+	pkg, err := Package(pkgPath, true)
+	assert.Nil(t, err, err)
+
+	dir := filepath.Join(t.TempDir(), "out", "pkg")
+
+	results, err := pkg.Write(dir, true, WriteOptions{DryRun: true})
+	assert.Nil(t, err, err)
+	assert.Len(t, results, 2)
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+
+	results, err = pkg.Write(dir, true, WriteOptions{})
+	assert.Nil(t, err, err)
+	assert.Len(t, results, 2)
+
+	for _, result := range results {
+		assert.True(t, result.Changed)
+
+		content, err := ioutil.ReadFile(result.Path)
+		assert.Nil(t, err, err)
+		assert.Equal(t, string(result.Content), string(content))
+
+		fileInfo, err := os.Stat(result.Path)
+		assert.Nil(t, err, err)
+		assert.Equal(t, os.FileMode(0644), fileInfo.Mode().Perm())
+	}
+	assert.Equal(t, filepath.Join(dir, "greet.go"), results[0].Path)
+	assert.Equal(t, filepath.Join(dir, "log", "log.go"), results[1].Path)
+
+	err = pkg.WritePkg(dir, true)
+	assert.Nil(t, err, err)
+}
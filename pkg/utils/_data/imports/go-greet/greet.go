@@ -0,0 +1,6 @@
+package greet
+
+// Hello return a greeting for the given name.
+func Hello(name string) string {
+	return "Hello, " + name
+}
@@ -0,0 +1,6 @@
+package greeting
+
+// Salute return a greeting for the given name.
+func Salute(name string) string {
+	return "Hi, " + name
+}
@@ -0,0 +1,34 @@
+package rename
+
+import (
+	"fmt"
+
+	"github.com/negrel/asttk/pkg/utils/_data/rename/log"
+)
+
+type greeter struct {
+	prefix string
+}
+
+func (g greeter) greet(name string) string {
+	return g.prefix + name
+}
+
+// Greet all the given person name.
+func Greet(names ...string) {
+	g := greeter{prefix: "Hello, "}
+
+	for _, name := range names {
+		log.Print(g.greet(name))
+		fmt.Println(greet(name))
+	}
+}
+
+func greet(name string) string {
+	return "Hi, " + name
+}
+
+func hello() {
+	greet := "Hello"
+	fmt.Println(greet)
+}
@@ -2,39 +2,201 @@ package utils
 
 import (
 	"go/ast"
+	"strings"
 
 	"github.com/negrel/asttk/pkg/inspector"
 )
 
-func RemoveComments(filter func(comment string) bool) inspector.Inspector {
-	return func(node ast.Node) (recursive bool) {
-		recursive = true
+// CommentFilter return true if the given comment must be removed. owner is the
+// node documented by the comment or the node the comment is a line comment of,
+// it is nil for the other comments.
+type CommentFilter func(comment *ast.Comment, owner ast.Node) bool
 
-		if node == nil {
-			return
+type commentOwner struct {
+	node ast.Node
+	doc  bool
+}
+
+// RemoveComments return an Inspector that removes the comments of the inspected
+// ast.File for which the given filter return true.
+func RemoveComments(filter CommentFilter) inspector.Inspector {
+	return removeComments(func(comment *ast.Comment, owner commentOwner) bool {
+		return filter(comment, owner.node)
+	})
+}
+
+// RemoveAllComments return an Inspector that removes every comment.
+func RemoveAllComments() inspector.Inspector {
+	return RemoveComments(func(_ *ast.Comment, _ ast.Node) bool { return true })
+}
+
+// RemoveDocComments return an Inspector that removes the doc comments of
+// the package, declarations, specs and fields. Directives are kept.
+func RemoveDocComments() inspector.Inspector {
+	return removeComments(func(comment *ast.Comment, owner commentOwner) bool {
+		return owner.doc && !IsDirective(comment, owner.node)
+	})
+}
+
+// RemoveNonDocComments return an Inspector that removes every comment except
+// doc comments and directives.
+func RemoveNonDocComments() inspector.Inspector {
+	return removeComments(func(comment *ast.Comment, owner commentOwner) bool {
+		return !owner.doc && !IsDirective(comment, owner.node)
+	})
+}
+
+// RemoveNonDirectiveComments return an Inspector that removes every comment
+// except compiler and tool directives (//go:build, //go:generate, //nolint...)
+// and cgo preambles.
+func RemoveNonDirectiveComments() inspector.Inspector {
+	return removeComments(func(comment *ast.Comment, owner commentOwner) bool {
+		return !IsDirective(comment, owner.node)
+	})
+}
+
+func removeComments(filter func(comment *ast.Comment, owner commentOwner) bool) inspector.Inspector {
+	return func(node ast.Node) bool {
+		file, isFile := node.(*ast.File)
+		if !isFile {
+			return true
 		}
 
-		if file, isFile := node.(*ast.File); isFile {
-			file.Comments = nil
-			file.Doc = nil
+		owners := commentOwners(file)
+
+		groups := file.Comments[:0]
+		for _, group := range file.Comments {
+			owner := owners[group]
+
+			list := group.List[:0]
+			for _, comment := range group.List {
+				if !filter(comment, owner) {
+					list = append(list, comment)
+				}
+			}
+			group.List = list
+
+			if len(group.List) != 0 {
+				groups = append(groups, group)
+				continue
+			}
+
+			if owner.node != nil {
+				detachComment(owner.node, group)
+			}
 		}
+		file.Comments = groups
+
+		return false
+	}
+}
 
-		commentGroup, isCommentGroup := node.(*ast.CommentGroup)
-		if !isCommentGroup {
-			return
+// commentOwners return the node owning each doc and line comment group of
+// the given file.
+func commentOwners(file *ast.File) map[*ast.CommentGroup]commentOwner {
+	owners := make(map[*ast.CommentGroup]commentOwner)
+	add := func(node ast.Node, doc, comment *ast.CommentGroup) {
+		if doc != nil {
+			owners[doc] = commentOwner{node: node, doc: true}
+		}
+		if comment != nil {
+			owners[comment] = commentOwner{node: node}
 		}
+	}
 
-		for i, comment := range commentGroup.List {
-			if filter(comment.Text) {
-				commentGroup.List = append(commentGroup.List[:i], commentGroup.List[i+1:]...)
-			}
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.File:
+			add(n, n.Doc, nil)
+		case *ast.GenDecl:
+			add(n, n.Doc, nil)
+		case *ast.FuncDecl:
+			add(n, n.Doc, nil)
+		case *ast.Field:
+			add(n, n.Doc, n.Comment)
+		case *ast.ImportSpec:
+			add(n, n.Doc, n.Comment)
+		case *ast.ValueSpec:
+			add(n, n.Doc, n.Comment)
+		case *ast.TypeSpec:
+			add(n, n.Doc, n.Comment)
 		}
 
-		return
+		return true
+	})
+
+	return owners
+}
+
+// detachComment remove the given comment group from the Doc or Comment
+// field of its owner.
+func detachComment(owner ast.Node, group *ast.CommentGroup) {
+	switch n := owner.(type) {
+	case *ast.File:
+		n.Doc = nil
+	case *ast.GenDecl:
+		n.Doc = nil
+	case *ast.FuncDecl:
+		n.Doc = nil
+	case *ast.Field:
+		if n.Doc == group {
+			n.Doc = nil
+		} else {
+			n.Comment = nil
+		}
+	case *ast.ImportSpec:
+		if n.Doc == group {
+			n.Doc = nil
+		} else {
+			n.Comment = nil
+		}
+	case *ast.ValueSpec:
+		if n.Doc == group {
+			n.Doc = nil
+		} else {
+			n.Comment = nil
+		}
+	case *ast.TypeSpec:
+		if n.Doc == group {
+			n.Doc = nil
+		} else {
+			n.Comment = nil
+		}
 	}
 }
 
-// RemoveAllComments return an Inspector
-func RemoveAllComments() inspector.Inspector {
-	return RemoveComments(func(_ string) bool { return true })
+var directivePrefixes = []string{
+	"//go:",
+	"//line ",
+	"/*line ",
+	"//export ",
+	"//extern ",
+	"//nolint",
+	"//lint:",
+	"// +build",
+}
+
+// IsDirective return true if the given comment is a compiler or tool
+// directive or a cgo preamble.
+func IsDirective(comment *ast.Comment, owner ast.Node) bool {
+	for _, prefix := range directivePrefixes {
+		if strings.HasPrefix(comment.Text, prefix) {
+			return true
+		}
+	}
+
+	// cgo preamble
+	switch n := owner.(type) {
+	case *ast.ImportSpec:
+		return importPath(n) == "C"
+
+	case *ast.GenDecl:
+		for _, spec := range n.Specs {
+			if importSpec, isImport := spec.(*ast.ImportSpec); isImport && importPath(importSpec) == "C" {
+				return true
+			}
+		}
+	}
+
+	return false
 }
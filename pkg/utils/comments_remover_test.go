@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"go/ast"
 	"path/filepath"
 	"testing"
 
@@ -25,7 +26,7 @@ func TestRemoveComments(t *testing.T) {
 	assert.Nil(t, err)
 
 	editor := inspector.New(
-		RemoveComments(func(_ string) bool { return true }),
+		RemoveComments(func(_ *ast.Comment, _ ast.Node) bool { return true }),
 	)
 	for i, file := range pkg.Files {
 		editor.Inspect(file.AST())
@@ -40,3 +41,154 @@ func TestRemoveComments(t *testing.T) {
 		assert.EqualValues(t, string(expectedResult), string(actualResult))
 	}
 }
+
+var commentsModesSrc = `//go:build linux
+
+// Package main doc.
+package main
+
+/*
+#include <stdio.h>
+*/
+import "C"
+
+//go:generate echo generate
+
+// logger doc.
+type logger struct {
+	prefix string // prefix of each log
+}
+
+// main doc.
+//
+//go:noinline
+func main() {
+	// our logger
+	log := &logger{} //nolint:all
+	_ = log
+}
+`
+
+func TestRemoveComments_Modes(t *testing.T) {
+	tests := []struct {
+		name     string
+		editor   inspector.Inspector
+		expected string
+	}{
+		{
+			name:   "Doc",
+			editor: RemoveDocComments(),
+			expected: `//go:build linux
+
+package main
+
+/*
+#include <stdio.h>
+*/
+import "C"
+
+//go:generate echo generate
+
+type logger struct {
+	prefix string // prefix of each log
+}
+
+//go:noinline
+func main() {
+	// our logger
+	log := &logger{} //nolint:all
+	_ = log
+}
+`,
+		},
+		{
+			name:   "NonDoc",
+			editor: RemoveNonDocComments(),
+			expected: `//go:build linux
+
+// Package main doc.
+package main
+
+/*
+#include <stdio.h>
+*/
+import "C"
+
+//go:generate echo generate
+
+// logger doc.
+type logger struct {
+	prefix string
+}
+
+// main doc.
+//
+//go:noinline
+func main() {
+
+	log := &logger{} //nolint:all
+	_ = log
+}
+`,
+		},
+		{
+			name:   "NonDirective",
+			editor: RemoveNonDirectiveComments(),
+			expected: `//go:build linux
+
+package main
+
+/*
+#include <stdio.h>
+*/
+import "C"
+
+//go:generate echo generate
+
+type logger struct {
+	prefix string
+}
+
+//go:noinline
+func main() {
+
+	log := &logger{} //nolint:all
+	_ = log
+}
+`,
+		},
+	}
+
+	for i, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filePath := filepath.Join("_data", "comments", "modes", fmt.Sprintf("case_%v.go", i))
+			file, err := parse.FileFromSource(filePath, []byte(commentsModesSrc))
+			assert.Nil(t, err, err)
+
+			inspector.New(test.editor).Inspect(file.AST())
+
+			actualResult, err := file.Bytes()
+			assert.Nil(t, err, err)
+
+			assert.Equal(t, test.expected, string(actualResult))
+		})
+	}
+}
+
+func TestRemoveComments_Filter(t *testing.T) {
+	filePath := filepath.Join("_data", "comments", "modes", "filter.go")
+	file, err := parse.FileFromSource(filePath, []byte(commentsModesSrc))
+	assert.Nil(t, err, err)
+
+	owners := make(map[string]ast.Node)
+	inspector.New(RemoveComments(func(comment *ast.Comment, owner ast.Node) bool {
+		owners[comment.Text] = owner
+		return false
+	})).Inspect(file.AST())
+
+	assert.Equal(t, file.AST(), owners["// Package main doc."])
+	assert.IsType(t, &ast.GenDecl{}, owners["// logger doc."])
+	assert.IsType(t, &ast.Field{}, owners["// prefix of each log"])
+	assert.IsType(t, &ast.FuncDecl{}, owners["//go:noinline"])
+	assert.Nil(t, owners["// our logger"])
+}
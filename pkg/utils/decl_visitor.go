@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"go/ast"
+	"go/token"
+
+	"github.com/negrel/asttk/pkg/inspector"
+)
+
+// DeclVisitor call a callback per kind of top-level declaration of a file.
+// The callbacks are optional. A callback returning false prevents the Lead from
+// inspecting the declaration, the declarations of the kinds without callback are
+// inspected if the Lead isn't nil.
+type DeclVisitor struct {
+	// Funcs is called on the function declarations without receiver.
+	Funcs func(decl *ast.FuncDecl) bool
+	// Methods is called once per receiver type, with the name of the type and
+	// its methods in the order of the file.
+	Methods func(recv string, methods []*ast.FuncDecl) bool
+	// Types is called on every type spec with its declaration.
+	Types func(spec *ast.TypeSpec, decl *ast.GenDecl) bool
+	// Consts is called on every constant spec with its declaration.
+	Consts func(spec *ast.ValueSpec, decl *ast.GenDecl) bool
+	// Vars is called on every variable spec with its declaration.
+	Vars func(spec *ast.ValueSpec, decl *ast.GenDecl) bool
+	// Imports is called on every import spec with its declaration.
+	Imports func(spec *ast.ImportSpec, decl *ast.GenDecl) bool
+
+	// Lead, if not nil, inspect the declarations and specs accepted by
+	// the callbacks.
+	Lead *inspector.Lead
+}
+
+// Inspector return an Inspector that visit the declarations of the inspected
+// ast.File and skip the rest of the AST.
+func (v *DeclVisitor) Inspector() inspector.Inspector {
+	return func(node ast.Node) bool {
+		if file, isFile := node.(*ast.File); isFile {
+			v.Visit(file)
+		}
+
+		return false
+	}
+}
+
+// Visit call the callbacks on the top-level declarations of the given file.
+// Methods are visited once all the other declarations were.
+func (v *DeclVisitor) Visit(file *ast.File) {
+	var receivers []string
+	methods := make(map[string][]*ast.FuncDecl)
+
+	for _, d := range file.Decls {
+		switch decl := d.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil {
+				v.visit(decl, v.Funcs == nil || v.Funcs(decl))
+				continue
+			}
+
+			recv := recvName(decl)
+			if _, ok := methods[recv]; !ok {
+				receivers = append(receivers, recv)
+			}
+			methods[recv] = append(methods[recv], decl)
+
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				v.visit(spec, v.visitSpec(spec, decl))
+			}
+		}
+	}
+
+	for _, recv := range receivers {
+		ok := v.Methods == nil || v.Methods(recv, methods[recv])
+		for _, method := range methods[recv] {
+			v.visit(method, ok)
+		}
+	}
+}
+
+func (v *DeclVisitor) visitSpec(spec ast.Spec, decl *ast.GenDecl) bool {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return v.Types == nil || v.Types(s, decl)
+
+	case *ast.ImportSpec:
+		return v.Imports == nil || v.Imports(s, decl)
+
+	case *ast.ValueSpec:
+		if decl.Tok == token.CONST {
+			return v.Consts == nil || v.Consts(s, decl)
+		}
+		return v.Vars == nil || v.Vars(s, decl)
+	}
+
+	return true
+}
+
+func (v *DeclVisitor) visit(node ast.Node, ok bool) {
+	if ok && v.Lead != nil {
+		v.Lead.Inspect(node)
+	}
+}
+
+// recvName return the name of the receiver type of the given method.
+func recvName(decl *ast.FuncDecl) string {
+	expr := decl.Recv.List[0].Type
+
+	for {
+		switch e := expr.(type) {
+		case *ast.StarExpr:
+			expr = e.X
+		case *ast.ParenExpr:
+			expr = e.X
+		case *ast.IndexExpr:
+			expr = e.X
+		case *ast.IndexListExpr:
+			expr = e.X
+		case *ast.Ident:
+			return e.Name
+		default:
+			return ""
+		}
+	}
+}
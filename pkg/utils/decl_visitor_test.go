@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"go/ast"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/negrel/asttk/pkg/inspector"
+	"github.com/negrel/asttk/pkg/parse"
+)
+
+const declsSrc = `package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const greeting, farewell = "Hello", "Bye"
+
+var names = []string{"World"}
+
+type greeter struct{}
+
+func (g *greeter) greet(name string) { fmt.Println(greeting, name) }
+
+type list[T any] []T
+
+func (l list[T]) len() int { return len(l) }
+
+func (g greeter) bye(name string) { fmt.Println(farewell, strings.ToUpper(name)) }
+
+func main() {
+	(&greeter{}).greet(names[0])
+}
+`
+
+func loadDeclsFile(t *testing.T) *parse.GoFile {
+	file, err := parse.FileFromSource(filepath.Join("_data", "decls", "main.go"), []byte(declsSrc))
+	assert.Nil(t, err, err)
+
+	return file
+}
+
+func TestApplyOnTopDecl(t *testing.T) {
+	file := loadDeclsFile(t)
+
+	var decls, funcNames []string
+	inspector.New(ApplyOnTopDecl(
+		func(node ast.Node) bool {
+			if decl, isFuncDecl := node.(*ast.FuncDecl); isFuncDecl {
+				decls = append(decls, decl.Name.Name)
+			}
+
+			return false
+		},
+		func(node ast.Node) bool {
+			if ident, isIdent := node.(*ast.Ident); isIdent {
+				funcNames = append(funcNames, ident.Name)
+			}
+
+			_, isGenDecl := node.(*ast.GenDecl)
+			return !isGenDecl
+		},
+	)).Inspect(file.AST())
+
+	assert.Equal(t, []string{"greet", "len", "bye", "main"}, decls)
+	assert.Contains(t, funcNames, "greet")
+	assert.Contains(t, funcNames, "ToUpper")
+	assert.NotContains(t, funcNames, "any")
+}
+
+func TestDeclVisitor(t *testing.T) {
+	file := loadDeclsFile(t)
+
+	var visited []string
+	var idents []string
+	visitor := &DeclVisitor{
+		Funcs: func(decl *ast.FuncDecl) bool {
+			visited = append(visited, "func "+decl.Name.Name)
+			return true
+		},
+		Methods: func(recv string, methods []*ast.FuncDecl) bool {
+			for _, method := range methods {
+				visited = append(visited, "method "+recv+"."+method.Name.Name)
+			}
+			return recv == "list"
+		},
+		Types: func(spec *ast.TypeSpec, _ *ast.GenDecl) bool {
+			visited = append(visited, "type "+spec.Name.Name)
+			return false
+		},
+		Consts: func(spec *ast.ValueSpec, _ *ast.GenDecl) bool {
+			for _, name := range spec.Names {
+				visited = append(visited, "const "+name.Name)
+			}
+			return false
+		},
+		Imports: func(spec *ast.ImportSpec, _ *ast.GenDecl) bool {
+			visited = append(visited, "import "+importPath(spec))
+			return false
+		},
+		Lead: inspector.New(func(node ast.Node) bool {
+			if ident, isIdent := node.(*ast.Ident); isIdent {
+				idents = append(idents, ident.Name)
+			}
+			return true
+		}),
+	}
+	inspector.New(visitor.Inspector()).Inspect(file.AST())
+
+	assert.Equal(t, []string{
+		"import fmt",
+		"import strings",
+		"const greeting",
+		"const farewell",
+		"type greeter",
+		"type list",
+		"func main",
+		"method greeter.greet",
+		"method greeter.bye",
+		"method list.len",
+	}, visited)
+
+	// Vars have no callback, the Lead inspects them.
+	assert.Contains(t, idents, "names")
+	// Inspected methods and functions.
+	assert.Contains(t, idents, "len")
+	assert.Contains(t, idents, "main")
+	// Skipped declarations.
+	assert.NotContains(t, idents, "greeting")
+	assert.NotContains(t, idents, "ToUpper")
+	assert.NotContains(t, idents, "any")
+}
@@ -7,15 +7,15 @@ import (
 )
 
 // ChangePackage return an Inspector function that will change the package
-// of a file.
+// of a file. Importers of the package are not updated, see RenamePackage.
 func ChangePackage(name string) inspector.Inspector {
 	return func(node ast.Node) bool {
-		pkg, isPackage := node.(*ast.Package)
-		if !isPackage {
+		file, isFile := node.(*ast.File)
+		if !isFile {
 			return false
 		}
 
-		pkg.Name = name
+		file.Name.Name = name
 
 		return false
 	}
@@ -24,23 +24,21 @@ func ChangePackage(name string) inspector.Inspector {
 // ApplyOnTopDecl wraps the given Inspectors and call them on
 // every ast.File.Decls node. Inspectors wrapped by this helper
 // will be called before other inspectors of your inspector.Lead.
+// An Inspector returning true walks the children of the declaration,
+// see DeclVisitor to dispatch the declarations by kind.
 func ApplyOnTopDecl(inspectors ...inspector.Inspector) inspector.Inspector {
-	wrappers := make([]inspector.Inspector, len(inspectors))
-
-	for i, isp := range inspectors {
-		wrappers[i] = func(node ast.Node) bool {
-			file, isFile := node.(*ast.File)
-			if !isFile {
-				return false
-			}
-
-			for _, decl := range file.Decls {
-				isp(decl)
-			}
+	lead := inspector.New(inspectors...)
 
+	return func(node ast.Node) bool {
+		file, isFile := node.(*ast.File)
+		if !isFile {
 			return false
 		}
-	}
 
-	return inspector.Lieutenant(wrappers...)
+		for _, decl := range file.Decls {
+			lead.Inspect(decl)
+		}
+
+		return false
+	}
 }
@@ -14,7 +14,8 @@ type funcRenamer struct {
 }
 
 // RenameFunc return two inspector.Inspector, one to rename function declaration and another one
-// to rename function call.
+// to rename function call. Functions are matched by name only, see RenameFuncs
+// for a type-aware alternative.
 func RenameFunc(filter func(name string) (replaceName string, ok bool)) (renameFuncDecl, renameFuncCall inspector.Inspector) {
 	f := &funcRenamer{
 		filter: filter,
@@ -0,0 +1,461 @@
+package utils
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/negrel/asttk/pkg/inspector"
+	"github.com/negrel/asttk/pkg/parse"
+)
+
+// ImportManager add, rename, rewrite and group the imports of a parse.GoFile.
+// Every edit keeps ast.File.Imports in sync with the import declarations.
+type ImportManager struct {
+	file *parse.GoFile
+}
+
+// NewImportManager return an ImportManager for the given file.
+func NewImportManager(file *parse.GoFile) *ImportManager {
+	return &ImportManager{
+		file: file,
+	}
+}
+
+// Add import the package with the given path, if it isn't already, and return
+// the name to use to refer to it. If the package name conflicts with another
+// identifier of the file, the import is named with a non-conflicting alias.
+//
+// The import is inserted next to the imports with the closest path, call Group
+// to sort the imports. Add may insert a new import declaration in the file, it
+// must not be called while the declarations of the file are inspected.
+func (im *ImportManager) Add(path string) (name string, err error) {
+	if path == "" {
+		return "", fmt.Errorf("the given import path is empty")
+	}
+
+	for _, spec := range im.file.AST().Imports {
+		if importPath(spec) != path {
+			continue
+		}
+
+		if n := im.name(spec); n != "_" && n != "." {
+			return n, nil
+		}
+	}
+
+	name = assumedPackageName(path)
+	alias := ""
+	if im.conflict(name) {
+		alias = im.freeName(name)
+		name = alias
+	}
+
+	astutil.AddNamedImport(im.file.FileSet(), im.file.AST(), alias, path)
+
+	return name, nil
+}
+
+// SetName name the import with the given path and return an inspector.Inspector
+// that rename the qualified identifiers referring to it. The returned inspector
+// must be run on the file for the references to be updated.
+func (im *ImportManager) SetName(path, name string) (inspector.Inspector, error) {
+	if !token.IsIdentifier(name) {
+		return nil, fmt.Errorf("%v is an invalid import name", name)
+	}
+
+	spec := im.find(path)
+	if spec == nil {
+		return nil, fmt.Errorf("%v is not imported", path)
+	}
+
+	oldName := im.name(spec)
+	if oldName == name {
+		return func(_ ast.Node) bool { return false }, nil
+	}
+
+	if im.conflict(name) {
+		return nil, fmt.Errorf("import name %v conflict with another identifier", name)
+	}
+
+	pkgName := im.pkgName(spec)
+	if spec.Name == nil {
+		spec.Name = &ast.Ident{NamePos: spec.Path.Pos()}
+	}
+	spec.Name.Name = name
+
+	return im.renameReferences(pkgName, oldName, name), nil
+}
+
+// renameReferences return an inspector.Inspector that rename the qualified
+// identifiers referring to the given imported package from oldName to newName.
+func (im *ImportManager) renameReferences(pkgName *types.PkgName, oldName, newName string) inspector.Inspector {
+	return func(node ast.Node) (recursive bool) {
+		recursive = true
+
+		sel, isSelector := node.(*ast.SelectorExpr)
+		if !isSelector {
+			return
+		}
+
+		ident, isIdent := sel.X.(*ast.Ident)
+		if !isIdent || ident.Name != oldName {
+			return
+		}
+
+		if info := im.info(); pkgName != nil && info != nil {
+			if info.Uses[ident] != pkgName {
+				return
+			}
+		} else if ident.Obj != nil {
+			// Without type information, only unresolved identifiers
+			// can refer to a package.
+			return
+		}
+
+		ident.Name = newName
+
+		return
+	}
+}
+
+// Rewrite change the path of the import with the given path. If the package
+// name of the new path differs, the import is named after the old package
+// so the references remain valid.
+func (im *ImportManager) Rewrite(oldPath, newPath string) bool {
+	spec := im.find(oldPath)
+	if spec == nil {
+		return false
+	}
+
+	if oldName := im.name(spec); spec.Name == nil && oldName != assumedPackageName(newPath) {
+		spec.Name = &ast.Ident{
+			NamePos: spec.Path.Pos(),
+			Name:    oldName,
+		}
+	}
+
+	return astutil.RewriteImport(im.file.FileSet(), im.file.AST(), oldPath, newPath)
+}
+
+// RewriteImport change the path of the import with the given path in every
+// file of the given package. It return true if at least one file was edited.
+func RewriteImport(pkg *parse.GoPackage, oldPath, newPath string) bool {
+	rewritten := false
+
+	for _, file := range pkg.AllFiles() {
+		if NewImportManager(file).Rewrite(oldPath, newPath) {
+			rewritten = true
+		}
+	}
+
+	return rewritten
+}
+
+// Group merge the import declarations of the file and sort the imports in
+// three blocks separated by a blank line: standard library, third-party and
+// local packages, like goimports does. Local packages are the one whose path
+// starts with the given prefix, if it isn't empty. The imports are sorted by
+// path inside each block. Comments attached to an import follow it, free-floating
+// comments inside the import declarations are dropped.
+//
+// The imports are moved to the lines of the import declarations, the blocks are
+// only separated by a blank line if the declarations span enough lines to hold
+// them. A single import declaration with a single import is left untouched.
+func (im *ImportManager) Group(localPrefix string) {
+	var decls []*ast.GenDecl
+	specCount := 0
+	for _, d := range im.file.AST().Decls {
+		if decl, ok := d.(*ast.GenDecl); ok && decl.Tok == token.IMPORT {
+			decls = append(decls, decl)
+			specCount += len(decl.Specs)
+		}
+	}
+	if len(decls) == 0 || (len(decls) == 1 && specCount <= 1) {
+		return
+	}
+
+	groups := make([][]*ast.ImportSpec, 3)
+	for _, decl := range decls {
+		for _, s := range decl.Specs {
+			spec := s.(*ast.ImportSpec)
+			group := importGroup(importPath(spec), localPrefix)
+			groups[group] = append(groups[group], spec)
+		}
+	}
+
+	for _, group := range groups {
+		sort.SliceStable(group, func(i, j int) bool {
+			return importPath(group[i]) < importPath(group[j])
+		})
+	}
+
+	im.layoutImports(decls, groups)
+
+	var specs []ast.Spec
+	var imports []*ast.ImportSpec
+	for _, group := range groups {
+		for _, spec := range group {
+			specs = append(specs, spec)
+			imports = append(imports, spec)
+		}
+	}
+
+	first := decls[0]
+	first.Specs = specs
+	im.file.AST().Imports = imports
+
+	fileDecls := im.file.AST().Decls[:0]
+	for _, d := range im.file.AST().Decls {
+		if decl, ok := d.(*ast.GenDecl); ok && decl.Tok == token.IMPORT && decl != first {
+			continue
+		}
+		fileDecls = append(fileDecls, d)
+	}
+	im.file.AST().Decls = fileDecls
+}
+
+// layoutImports move the given import groups, with their comments, to the lines
+// of the import declarations: each doc comment and import get a line, the
+// trailing comments stay on the line of their import. A line is left blank
+// between the groups if there is enough lines. The line table of the file isn't
+// modified.
+func (im *ImportManager) layoutImports(decls []*ast.GenDecl, groups [][]*ast.ImportSpec) {
+	tokFile := im.file.FileSet().File(decls[0].Pos())
+	first, last := decls[0], decls[len(decls)-1]
+	start, end := first.Pos(), last.End()
+
+	// The lines between the parenthesis, or the lines of the imports.
+	firstLine := tokFile.Line(specStart(first.Specs[0].(*ast.ImportSpec)))
+	if first.Lparen.IsValid() {
+		firstLine = tokFile.Line(first.Lparen) + 1
+	}
+	lastLine := tokFile.Line(specEnd(last.Specs[len(last.Specs)-1].(*ast.ImportSpec)))
+	if last.Rparen.IsValid() {
+		lastLine = tokFile.Line(last.Rparen) - 1
+	}
+
+	needed, blocks := 0, 0
+	for _, group := range groups {
+		if len(group) > 0 {
+			blocks++
+		}
+		for _, spec := range group {
+			needed++
+			if spec.Doc != nil {
+				needed += len(spec.Doc.List)
+			}
+		}
+	}
+	separate := needed+blocks-1 <= lastLine-firstLine+1
+
+	line := firstLine
+	// nextLine return the start of the next free line, the last line is
+	// reused if there is not enough lines.
+	nextLine := func() token.Pos {
+		if line > lastLine {
+			return tokFile.LineStart(lastLine)
+		}
+
+		line++
+		return tokFile.LineStart(line - 1)
+	}
+
+	kept := make(map[*ast.CommentGroup]bool)
+	started := false
+	for _, group := range groups {
+		if len(group) == 0 {
+			continue
+		}
+		if started && separate {
+			line++
+		}
+		started = true
+
+		for _, spec := range group {
+			if spec.Doc != nil {
+				kept[spec.Doc] = true
+				for _, comment := range spec.Doc.List {
+					comment.Slash = nextLine()
+				}
+			}
+
+			pos := nextLine()
+			if spec.Name != nil {
+				spec.Name.NamePos = pos
+			}
+			spec.Path.ValuePos = pos
+			// The path doesn't fit in the line start, the spec end is
+			// overridden so it doesn't span several lines.
+			spec.EndPos = pos
+
+			// Comments at the position of the spec are printed after it.
+			if spec.Comment != nil {
+				kept[spec.Comment] = true
+				for _, comment := range spec.Comment.List {
+					comment.Slash = pos
+				}
+			}
+		}
+	}
+
+	if !first.Lparen.IsValid() {
+		first.Lparen = first.TokPos + token.Pos(len(token.IMPORT.String()))
+	}
+	first.Rparen = last.Rparen
+	if !first.Rparen.IsValid() {
+		first.Rparen = end - 1
+	}
+
+	// Drop free-floating comments of the import declarations.
+	comments := im.file.AST().Comments[:0]
+	for _, comment := range im.file.AST().Comments {
+		if comment.Pos() > start && comment.Pos() < end && !kept[comment] {
+			continue
+		}
+		comments = append(comments, comment)
+	}
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].Pos() < comments[j].Pos()
+	})
+	im.file.AST().Comments = comments
+}
+
+func (im *ImportManager) find(path string) *ast.ImportSpec {
+	for _, spec := range im.file.AST().Imports {
+		if importPath(spec) == path {
+			return spec
+		}
+	}
+
+	return nil
+}
+
+// name return the name used to refer to the package imported by the given spec,
+// the package name is only assumed from the path without type information.
+func (im *ImportManager) name(spec *ast.ImportSpec) string {
+	if spec.Name != nil {
+		return spec.Name.Name
+	}
+
+	if pkgName := im.pkgName(spec); pkgName != nil {
+		return pkgName.Name()
+	}
+
+	return assumedPackageName(importPath(spec))
+}
+
+func (im *ImportManager) info() *types.Info {
+	if pkg := im.file.Package(); pkg != nil {
+		return pkg.TypesInfo()
+	}
+
+	return nil
+}
+
+func (im *ImportManager) pkgName(spec *ast.ImportSpec) *types.PkgName {
+	info := im.info()
+	if info == nil {
+		return nil
+	}
+
+	var obj types.Object
+	if spec.Name != nil {
+		obj = info.Defs[spec.Name]
+	} else {
+		obj = info.Implicits[spec]
+	}
+
+	pkgName, _ := obj.(*types.PkgName)
+	return pkgName
+}
+
+// conflict return true if the given name is already used in the file or
+// declared in its package.
+func (im *ImportManager) conflict(name string) bool {
+	if pkg := im.file.Package(); pkg != nil && pkg.Types() != nil {
+		if pkg.Types().Scope().Lookup(name) != nil {
+			return true
+		}
+	}
+
+	used := false
+	inspector.New(func(node ast.Node) bool {
+		if ident, isIdent := node.(*ast.Ident); isIdent && ident.Name == name {
+			used = true
+		}
+
+		return !used
+	}).Inspect(im.file.AST())
+
+	if used {
+		return true
+	}
+
+	for _, spec := range im.file.AST().Imports {
+		if spec.Name == nil && im.name(spec) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (im *ImportManager) freeName(name string) string {
+	for i := 2; ; i++ {
+		alias := name + strconv.Itoa(i)
+		if !im.conflict(alias) {
+			return alias
+		}
+	}
+}
+
+// importGroup return 0 for standard library packages, 1 for third-party
+// packages and 2 for local packages.
+func importGroup(path, localPrefix string) int {
+	if localPrefix != "" && strings.HasPrefix(path, localPrefix) {
+		return 2
+	}
+
+	if firstElem := strings.Split(path, "/")[0]; !strings.Contains(firstElem, ".") {
+		return 0
+	}
+
+	return 1
+}
+
+// assumedPackageName return the assumed package name of the given import path,
+// following the goimports conventions: major version suffix and "go-" prefix
+// are ignored.
+func assumedPackageName(path string) string {
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+
+	if len(elems) > 1 && isMajorVersion(name) {
+		name = elems[len(elems)-2]
+	}
+
+	name = strings.TrimPrefix(name, "go-")
+	if i := strings.IndexFunc(name, func(r rune) bool {
+		return !(r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9'))
+	}); i >= 0 {
+		name = name[:i]
+	}
+
+	return name
+}
+
+func isMajorVersion(elem string) bool {
+	if len(elem) < 2 || elem[0] != 'v' {
+		return false
+	}
+
+	_, err := strconv.Atoi(elem[1:])
+	return err == nil
+}
@@ -0,0 +1,285 @@
+package utils
+
+import (
+	"go/format"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/negrel/asttk/pkg/inspector"
+	"github.com/negrel/asttk/pkg/parse"
+)
+
+func loadImportsFile(t *testing.T, src string) *parse.GoFile {
+	filePath := filepath.Join("_data", "imports", "manager", "main.go")
+	file, err := parse.FileFromSource(filePath, []byte(src))
+	assert.Nil(t, err, err)
+
+	return file
+}
+
+func assertSource(t *testing.T, expected string, file *parse.GoFile) {
+	expectedResult, err := format.Source([]byte(expected))
+	assert.Nil(t, err, err)
+
+	actualResult, err := file.Bytes()
+	assert.Nil(t, err, err)
+
+	assert.Equal(t, string(expectedResult), string(actualResult))
+}
+
+func TestImportManager_Add(t *testing.T) {
+	file := loadImportsFile(t, `package main
+
+import (
+	"fmt"
+)
+
+func greet() {
+	fmt.Println("Hello world")
+}
+`)
+	im := NewImportManager(file)
+
+	name, err := im.Add("fmt")
+	assert.Nil(t, err, err)
+	assert.Equal(t, "fmt", name)
+
+	name, err = im.Add("strings")
+	assert.Nil(t, err, err)
+	assert.Equal(t, "strings", name)
+
+	name, err = im.Add("github.com/negrel/asttk/pkg/utils/_data/imports/go-greet")
+	assert.Nil(t, err, err)
+	assert.Equal(t, "greet2", name)
+
+	name, err = im.Add("gopkg.in/yaml.v3")
+	assert.Nil(t, err, err)
+	assert.Equal(t, "yaml", name)
+
+	assert.Len(t, file.AST().Imports, 4)
+
+	// The added imports are on the line of fmt, there is no room for a
+	// blank line between the blocks.
+	im.Group("")
+
+	assertSource(t, `package main
+
+import (
+	"fmt"
+	"strings"
+	greet2 "github.com/negrel/asttk/pkg/utils/_data/imports/go-greet"
+	"gopkg.in/yaml.v3"
+)
+
+func greet() {
+	fmt.Println("Hello world")
+}
+`, file)
+}
+
+func TestImportManager_SetName(t *testing.T) {
+	file := loadImportsFile(t, `package main
+
+import (
+	"fmt"
+)
+
+type printer struct {
+	fmt string
+}
+
+func main() {
+	p := printer{}
+	fmt.Println(p.fmt)
+}
+`)
+	im := NewImportManager(file)
+
+	_, err := im.SetName("fmt", "p")
+	assert.NotNil(t, err)
+
+	renameRefs, err := im.SetName("fmt", "f")
+	assert.Nil(t, err, err)
+	inspector.New(renameRefs).Inspect(file.AST())
+
+	assertSource(t, `package main
+
+import (
+	f "fmt"
+)
+
+type printer struct {
+	fmt string
+}
+
+func main() {
+	p := printer{}
+	f.Println(p.fmt)
+}
+`, file)
+}
+
+func TestImportManager_Rewrite(t *testing.T) {
+	file := loadImportsFile(t, `package main
+
+import (
+	"fmt"
+	"log"
+)
+
+func main() {
+	log.Println(fmt.Sprint("Hello world"))
+}
+`)
+	im := NewImportManager(file)
+
+	assert.False(t, im.Rewrite("os", "io"))
+	assert.True(t, im.Rewrite("log", "github.com/negrel/asttk/pkg/utils/_data/imports/go-greet"))
+	assert.Equal(t, "github.com/negrel/asttk/pkg/utils/_data/imports/go-greet", importPath(file.AST().Imports[1]))
+
+	assertSource(t, `package main
+
+import (
+	"fmt"
+	log "github.com/negrel/asttk/pkg/utils/_data/imports/go-greet"
+)
+
+func main() {
+	log.Println(fmt.Sprint("Hello world"))
+}
+`, file)
+}
+
+func TestImportManager_Group(t *testing.T) {
+	file := loadImportsFile(t, `// Package main doc.
+package main
+
+import (
+	"github.com/negrel/asttk/pkg/utils/_data/imports/go-greet"
+	// fmt doc
+	"fmt" // fmt comment
+	"gopkg.in/yaml.v3"
+)
+
+import "os"
+
+// main doc.
+func main() {
+	out, _ := yaml.Marshal(greet.Hello("World"))
+	fmt.Fprintln(os.Stdout, string(out)) // print
+}
+`)
+	im := NewImportManager(file)
+
+	im.Group("github.com/negrel/asttk")
+
+	assert.Len(t, file.AST().Imports, 4)
+	assert.Equal(t, "fmt", importPath(file.AST().Imports[0]))
+	assert.Equal(t, "os", importPath(file.AST().Imports[1]))
+	assertSource(t, `// Package main doc.
+package main
+
+import (
+	// fmt doc
+	"fmt" // fmt comment
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/negrel/asttk/pkg/utils/_data/imports/go-greet"
+)
+
+// main doc.
+func main() {
+	out, _ := yaml.Marshal(greet.Hello("World"))
+	fmt.Fprintln(os.Stdout, string(out)) // print
+}
+`, file)
+}
+
+func TestImportManager_Group_SingleImport(t *testing.T) {
+	src := `package main
+
+import "os"
+
+func main() {
+	os.Exit(0)
+}
+`
+	file := loadImportsFile(t, src)
+
+	NewImportManager(file).Group("")
+
+	assertSource(t, src, file)
+}
+
+func TestImportManager_Group_SameLine(t *testing.T) {
+	file := loadImportsFile(t, `package main
+
+import ("os"; "fmt"; "gopkg.in/yaml.v3")
+
+func main() {
+	out, _ := yaml.Marshal("Hello world")
+	fmt.Fprintln(os.Stdout, string(out))
+}
+`)
+
+	NewImportManager(file).Group("")
+
+	assertSource(t, `package main
+
+import (
+	"fmt"
+	"os"
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	out, _ := yaml.Marshal("Hello world")
+	fmt.Fprintln(os.Stdout, string(out))
+}
+`, file)
+}
+
+func TestImportManager_PackageName(t *testing.T) {
+	pkg, err := parse.PackageFromOverlay(filepath.Join("_data", "imports", "manager"), map[string][]byte{
+		"main.go": []byte(`package main
+
+import "github.com/negrel/asttk/pkg/utils/_data/imports/salute"
+
+func main() {
+	println(greeting.Salute("World"))
+}
+`),
+	}, false)
+	assert.Nil(t, err, err)
+	file := pkg.Files[0]
+	im := NewImportManager(file)
+
+	// The package name differs from the last element of its path.
+	name, err := im.Add("github.com/negrel/asttk/pkg/utils/_data/imports/salute")
+	assert.Nil(t, err, err)
+	assert.Equal(t, "greeting", name)
+
+	renamer, err := im.SetName("github.com/negrel/asttk/pkg/utils/_data/imports/salute", "hello")
+	assert.Nil(t, err, err)
+	inspector.New(renamer).Inspect(file.AST())
+
+	assertSource(t, `package main
+
+import hello "github.com/negrel/asttk/pkg/utils/_data/imports/salute"
+
+func main() {
+	println(hello.Salute("World"))
+}
+`, file)
+}
+
+func TestAssumedPackageName(t *testing.T) {
+	assert.Equal(t, "fmt", assumedPackageName("fmt"))
+	assert.Equal(t, "yaml", assumedPackageName("gopkg.in/yaml.v3"))
+	assert.Equal(t, "foo", assumedPackageName("github.com/x/go-foo"))
+	assert.Equal(t, "mod", assumedPackageName("example.com/mod/v2"))
+}
@@ -0,0 +1,553 @@
+package utils
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"sort"
+
+	"github.com/negrel/asttk/pkg/inspector"
+	"github.com/negrel/asttk/pkg/parse"
+)
+
+type objectRenamer struct {
+	// renames map the key of the renamed objects to their new name.
+	renames  map[string]string
+	refs     []objectRef
+	selected map[*ast.Ident]bool
+}
+
+type objectRef struct {
+	pkg       *parse.GoPackage
+	ident     *ast.Ident
+	def       bool
+	qualified bool
+	newName   string
+}
+
+// objectRename is a rename requested to renameObjects.
+type objectRename struct {
+	obj     types.Object
+	newName string
+}
+
+// RenameObject rename the declaration of the given types.Object and every
+// reference to it in the given package and its sub-packages. The object must
+// come from the type information of one of those packages.
+// An error is returned and nothing is renamed if the new name is invalid, if
+// it would conflict with another declaration or if renaming a method would
+// stop a type implementing an interface referenced in the packages.
+func RenameObject(pkg *parse.GoPackage, obj types.Object, newName string) error {
+	return renameObjects(pkg, []objectRename{{obj: obj, newName: newName}})
+}
+
+// RenameFuncs rename the functions and methods declared in the given package
+// and its sub-packages for which the filter return a new name. Unlike RenameFunc,
+// every reference (method calls, qualified calls, function values) is renamed
+// and shadowing identifiers are left untouched.
+// The new names are checked against each other before renaming anything, an
+// error is returned and nothing is renamed if one of the renames conflict.
+func RenameFuncs(pkg *parse.GoPackage, filter func(fn *types.Func) (replaceName string, ok bool)) error {
+	var renames []objectRename
+
+	for _, p := range allPkgs(pkg) {
+		info := p.TypesInfo()
+		if info == nil {
+			continue
+		}
+
+		for _, file := range p.AllFiles() {
+			for _, decl := range file.AST().Decls {
+				funcDecl, isFuncDecl := decl.(*ast.FuncDecl)
+				if !isFuncDecl {
+					continue
+				}
+
+				fn, isFunc := info.Defs[funcDecl.Name].(*types.Func)
+				if !isFunc {
+					continue
+				}
+
+				newName, ok := filter(fn)
+				if ok && newName != "" {
+					renames = append(renames, objectRename{obj: fn, newName: newName})
+				}
+			}
+		}
+	}
+
+	return renameObjects(pkg, renames)
+}
+
+// renameObjects check every rename against the declarations of the packages
+// and against each other, and then rename all the references in a single pass.
+func renameObjects(pkg *parse.GoPackage, renames []objectRename) error {
+	r := &objectRenamer{
+		renames:  make(map[string]string),
+		selected: make(map[*ast.Ident]bool),
+	}
+
+	pkgs := allPkgs(pkg)
+	objects := make(map[string]types.Object)
+	for _, rename := range renames {
+		obj, newName := rename.obj, rename.newName
+
+		if !token.IsIdentifier(newName) {
+			return fmt.Errorf("%v is an invalid new name", newName)
+		}
+		if obj.Name() == newName {
+			continue
+		}
+		if obj.Pkg() == nil || !obj.Pos().IsValid() {
+			return fmt.Errorf("can't rename %v: predeclared object", obj.Name())
+		}
+		if _, isPkgName := obj.(*types.PkgName); isPkgName {
+			return fmt.Errorf("can't rename %v: package name", obj.Name())
+		}
+
+		key := ""
+		for _, p := range pkgs {
+			if p.TypesInfo() != nil && definedIn(p, obj) {
+				key = objectKey(p, obj)
+				break
+			}
+		}
+		if key == "" {
+			return fmt.Errorf("can't rename %v: object not found in package %v", obj.Name(), pkg.PkgPath())
+		}
+
+		if prev, ok := r.renames[key]; ok && prev != newName {
+			return fmt.Errorf("can't rename %v to both %v and %v", obj.Name(), prev, newName)
+		}
+		r.renames[key] = newName
+		objects[key] = obj
+	}
+	if len(r.renames) == 0 {
+		return nil
+	}
+
+	if err := r.checkRenames(objects, pkgs); err != nil {
+		return err
+	}
+
+	for _, p := range pkgs {
+		if p.TypesInfo() == nil {
+			continue
+		}
+
+		for _, file := range p.AllFiles() {
+			inspector.New(r.collectRefs(p)).Inspect(file.AST())
+		}
+	}
+
+	for _, ref := range r.refs {
+		if err := r.checkConflict(ref); err != nil {
+			return err
+		}
+	}
+
+	for _, ref := range r.refs {
+		ref.ident.Name = ref.newName
+	}
+
+	return nil
+}
+
+// checkRenames check that no two renamed objects get the same name in the
+// same scope, and that the renamed methods don't break an interface
+// implementation.
+func (r *objectRenamer) checkRenames(objects map[string]types.Object, pkgs []*parse.GoPackage) error {
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for i, key := range keys {
+		obj := objects[key]
+
+		for _, other := range keys[i+1:] {
+			otherObj := objects[other]
+			if r.renames[key] != r.renames[other] || !sameScope(obj, otherObj) {
+				continue
+			}
+
+			return fmt.Errorf("renaming %v and %v to %v conflict", obj.Name(), otherObj.Name(), r.renames[key])
+		}
+
+		if err := checkImplements(obj, r.renames[key], pkgs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *objectRenamer) collectRefs(pkg *parse.GoPackage) inspector.Inspector {
+	info := pkg.TypesInfo()
+
+	return func(node ast.Node) (recursive bool) {
+		recursive = true
+
+		if sel, isSel := node.(*ast.SelectorExpr); isSel {
+			r.selected[sel.Sel] = true
+			return
+		}
+
+		ident, isIdent := node.(*ast.Ident)
+		if !isIdent {
+			return
+		}
+
+		ref := objectRef{
+			pkg:       pkg,
+			ident:     ident,
+			qualified: r.selected[ident],
+		}
+
+		if obj := info.Defs[ident]; obj != nil {
+			if newName, ok := r.renames[objectKey(pkg, obj)]; ok {
+				ref.def, ref.newName = true, newName
+				r.refs = append(r.refs, ref)
+			}
+			return
+		}
+
+		// Embedded fields are both a definition (the field) and
+		// a use (the type).
+		if obj := info.Uses[ident]; obj != nil {
+			if newName, ok := r.renames[objectKey(pkg, obj)]; ok {
+				ref.newName = newName
+				r.refs = append(r.refs, ref)
+			}
+		}
+
+		return
+	}
+}
+
+func (r *objectRenamer) checkConflict(ref objectRef) error {
+	pos := ref.pkg.FileSet().Position(ref.ident.Pos())
+	obj := r.refObject(ref)
+
+	if obj.Pkg().Path() != ref.pkg.PkgPath() && !token.IsExported(ref.newName) {
+		return fmt.Errorf("%v: renaming %v to %v would make it unexported", pos, obj.Name(), ref.newName)
+	}
+
+	switch {
+	// Methods and fields.
+	case isMethodOrField(obj):
+		recv := receiver(obj)
+		if recv == nil {
+			break
+		}
+
+		conflict, _, _ := types.LookupFieldOrMethod(recv, true, obj.Pkg(), ref.newName)
+		if conflict != nil && !r.renamedAway(ref, conflict) {
+			return fmt.Errorf("%v: renaming %v to %v conflict with %v", pos, obj.Name(), ref.newName, conflict)
+		}
+
+		return nil
+
+	// Package level declarations.
+	case obj.Parent() == obj.Pkg().Scope():
+		if conflict := obj.Pkg().Scope().Lookup(ref.newName); conflict != nil && !r.renamedAway(ref, conflict) {
+			return fmt.Errorf("%v: renaming %v to %v conflict with %v", pos, obj.Name(), ref.newName, conflict)
+		}
+
+		if !ref.def {
+			break
+		}
+
+		// Imports are declared in the file scope.
+		for _, file := range ref.pkg.AllFiles() {
+			scope := ref.pkg.TypesInfo().Scopes[file.AST()]
+			if scope == nil {
+				continue
+			}
+
+			if conflict := scope.Lookup(ref.newName); conflict != nil && !r.renamedAway(ref, conflict) {
+				return fmt.Errorf("%v: renaming %v to %v conflict with %v", pos, obj.Name(), ref.newName, conflict)
+			}
+		}
+	}
+
+	// Qualified identifier are resolved in the imported package scope.
+	if ref.qualified {
+		return nil
+	}
+
+	scope := innermostScope(ref.pkg, ref.ident.Pos())
+	if scope == nil {
+		return nil
+	}
+
+	if _, conflict := scope.LookupParent(ref.newName, ref.ident.Pos()); conflict != nil && conflict != obj && !r.renamedAway(ref, conflict) {
+		return fmt.Errorf("%v: renaming %v to %v conflict with %v", pos, obj.Name(), ref.newName, conflict)
+	}
+
+	return nil
+}
+
+// renamedAway return true if the given conflicting object is renamed to
+// another name than the one of the reference.
+func (r *objectRenamer) renamedAway(ref objectRef, conflict types.Object) bool {
+	newName, ok := r.renames[objectKey(ref.pkg, conflict)]
+
+	return ok && newName != ref.newName
+}
+
+func (r *objectRenamer) refObject(ref objectRef) types.Object {
+	if ref.def {
+		return ref.pkg.TypesInfo().Defs[ref.ident]
+	}
+
+	return ref.pkg.TypesInfo().Uses[ref.ident]
+}
+
+// allPkgs return the given package, its external test package and its
+// sub-packages.
+func allPkgs(pkg *parse.GoPackage) []*parse.GoPackage {
+	pkgs := []*parse.GoPackage{pkg}
+	if xtest := pkg.XTest(); xtest != nil {
+		pkgs = append(pkgs, xtest)
+	}
+
+	for _, subPkg := range pkg.SubPkgs() {
+		pkgs = append(pkgs, allPkgs(subPkg)...)
+	}
+
+	return pkgs
+}
+
+// objectKey return a key that identify the given object across packages
+// loaded separately.
+func objectKey(pkg *parse.GoPackage, obj types.Object) string {
+	if !obj.Pos().IsValid() {
+		return ""
+	}
+
+	return fmt.Sprintf("%v#%v", pkg.FileSet().Position(obj.Pos()), obj.Name())
+}
+
+func definedIn(pkg *parse.GoPackage, obj types.Object) bool {
+	info := pkg.TypesInfo()
+
+	for _, def := range info.Defs {
+		if def == obj {
+			return true
+		}
+	}
+
+	for _, use := range info.Uses {
+		if use == obj {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isMethodOrField(obj types.Object) bool {
+	switch o := obj.(type) {
+	case *types.Func:
+		sig, _ := o.Type().(*types.Signature)
+		return sig != nil && sig.Recv() != nil
+
+	case *types.Var:
+		return o.IsField()
+	}
+
+	return false
+}
+
+// receiver return the named type that declare the given method or field.
+func receiver(obj types.Object) types.Type {
+	if fn, isFunc := obj.(*types.Func); isFunc {
+		return fn.Type().(*types.Signature).Recv().Type()
+	}
+
+	// Fields, look for a named struct type declaring it in the package.
+	scope := obj.Pkg().Scope()
+	for _, name := range scope.Names() {
+		typeName, isTypeName := scope.Lookup(name).(*types.TypeName)
+		if !isTypeName {
+			continue
+		}
+
+		st, isStruct := typeName.Type().Underlying().(*types.Struct)
+		if !isStruct {
+			continue
+		}
+
+		for i := 0; i < st.NumFields(); i++ {
+			if st.Field(i) == obj {
+				return typeName.Type()
+			}
+		}
+	}
+
+	return nil
+}
+
+func innermostScope(pkg *parse.GoPackage, pos token.Pos) *types.Scope {
+	for _, file := range pkg.AllFiles() {
+		if file.AST().Pos() > pos || pos > file.AST().End() {
+			continue
+		}
+
+		scope := pkg.TypesInfo().Scopes[file.AST()]
+		if scope == nil {
+			return nil
+		}
+
+		return scope.Innermost(pos)
+	}
+
+	return nil
+}
+
+// sameScope return true if the given objects would conflict if they had the
+// same name: they are declared in the same scope, or they are methods or fields
+// of the same type.
+func sameScope(obj, other types.Object) bool {
+	if isMethodOrField(obj) || isMethodOrField(other) {
+		if !isMethodOrField(obj) || !isMethodOrField(other) {
+			return false
+		}
+
+		recv, otherRecv := receiver(obj), receiver(other)
+		if recv == nil || otherRecv == nil {
+			return false
+		}
+
+		return types.Identical(derefType(recv), derefType(otherRecv))
+	}
+
+	return obj.Parent() != nil && obj.Parent() == other.Parent()
+}
+
+// checkImplements return an error if renaming the given method would stop a
+// type implementing an interface used in the packages.
+func checkImplements(obj types.Object, newName string, pkgs []*parse.GoPackage) error {
+	fn, isFunc := obj.(*types.Func)
+	if !isFunc || !isMethodOrField(obj) {
+		return nil
+	}
+
+	var ifaces []types.Type
+	var named []types.Type
+	seen := make(map[types.Type]bool)
+	for _, p := range pkgs {
+		info := p.TypesInfo()
+		if info == nil {
+			continue
+		}
+
+		for _, tv := range info.Types {
+			collectInterfaces(tv.Type, seen, &ifaces)
+		}
+		for _, def := range info.Defs {
+			if typeName, isTypeName := def.(*types.TypeName); isTypeName {
+				collectInterfaces(typeName.Type(), seen, &ifaces)
+				named = append(named, typeName.Type())
+			}
+		}
+	}
+
+	recv := derefType(receiver(obj))
+
+	// Renaming an interface method break the types implementing it.
+	if types.IsInterface(recv) {
+		iface := recv.Underlying().(*types.Interface)
+		for _, typ := range named {
+			if types.IsInterface(typ) {
+				continue
+			}
+
+			if types.Implements(typ, iface) || types.Implements(types.NewPointer(typ), iface) {
+				return fmt.Errorf("renaming %v to %v would stop %v implementing %v", fn.Name(), newName, typ, recv)
+			}
+		}
+
+		return nil
+	}
+
+	// The receiver and the types embedding it provide the method.
+	for _, typ := range append([]types.Type{recv}, named...) {
+		if types.IsInterface(typ) {
+			continue
+		}
+
+		for _, t := range []types.Type{typ, types.NewPointer(typ)} {
+			sel := types.NewMethodSet(t).Lookup(fn.Pkg(), fn.Name())
+			if sel == nil || sel.Obj() != fn {
+				continue
+			}
+
+			for _, iface := range ifaces {
+				method, _, _ := types.LookupFieldOrMethod(iface, false, fn.Pkg(), fn.Name())
+				if method == nil || !types.Implements(t, iface.Underlying().(*types.Interface)) {
+					continue
+				}
+
+				return fmt.Errorf("renaming %v to %v would stop %v implementing %v", fn.Name(), newName, t, iface)
+			}
+		}
+	}
+
+	return nil
+}
+
+// collectInterfaces add the interfaces with methods referenced by the given type
+// to ifaces.
+func collectInterfaces(typ types.Type, seen map[types.Type]bool, ifaces *[]types.Type) {
+	if typ == nil || seen[typ] {
+		return
+	}
+	seen[typ] = true
+
+	switch t := typ.(type) {
+	case *types.Named:
+		if iface, isIface := t.Underlying().(*types.Interface); isIface && iface.NumMethods() > 0 {
+			*ifaces = append(*ifaces, t)
+		} else if !isIface {
+			collectInterfaces(t.Underlying(), seen, ifaces)
+		}
+	case *types.Interface:
+		if t.NumMethods() > 0 {
+			*ifaces = append(*ifaces, t)
+		}
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			collectInterfaces(t.Field(i).Type(), seen, ifaces)
+		}
+	case *types.Pointer:
+		collectInterfaces(t.Elem(), seen, ifaces)
+	case *types.Slice:
+		collectInterfaces(t.Elem(), seen, ifaces)
+	case *types.Array:
+		collectInterfaces(t.Elem(), seen, ifaces)
+	case *types.Chan:
+		collectInterfaces(t.Elem(), seen, ifaces)
+	case *types.Map:
+		collectInterfaces(t.Key(), seen, ifaces)
+		collectInterfaces(t.Elem(), seen, ifaces)
+	case *types.Signature:
+		collectInterfaces(t.Params(), seen, ifaces)
+		collectInterfaces(t.Results(), seen, ifaces)
+	case *types.Tuple:
+		for i := 0; i < t.Len(); i++ {
+			collectInterfaces(t.At(i).Type(), seen, ifaces)
+		}
+	}
+}
+
+func derefType(typ types.Type) types.Type {
+	if ptr, isPtr := typ.(*types.Pointer); isPtr {
+		return ptr.Elem()
+	}
+
+	return typ
+}
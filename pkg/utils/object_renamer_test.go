@@ -0,0 +1,268 @@
+package utils
+
+import (
+	"go/types"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/negrel/asttk/pkg/parse"
+)
+
+func findFunc(pkg *parse.GoPackage, name string, method bool) *types.Func {
+	for ident, obj := range pkg.TypesInfo().Defs {
+		fn, isFunc := obj.(*types.Func)
+		if !isFunc || ident.Name != name {
+			continue
+		}
+
+		if isMethod := fn.Type().(*types.Signature).Recv() != nil; isMethod == method {
+			return fn
+		}
+	}
+
+	return nil
+}
+
+func pkgSource(t *testing.T, pkg *parse.GoPackage) string {
+	var src string
+
+	for _, file := range pkg.Files {
+		bytes, err := file.Bytes()
+		assert.Nil(t, err, err)
+		src += string(bytes)
+	}
+
+	return src
+}
+
+func loadRenamePkg(t *testing.T) *parse.GoPackage {
+	pkg, err := parse.Package(filepath.Join("_data", "rename"), true)
+	assert.Nil(t, err, err)
+	assert.Len(t, pkg.SubPkgs(), 1)
+
+	return pkg
+}
+
+func TestRenameObject_Method(t *testing.T) {
+	pkg := loadRenamePkg(t)
+
+	err := RenameObject(pkg, findFunc(pkg, "greet", true), "welcome")
+	assert.Nil(t, err, err)
+
+	src := pkgSource(t, pkg)
+	assert.Contains(t, src, "func (g greeter) welcome(name string) string {")
+	assert.Contains(t, src, "log.Print(g.welcome(name))")
+	// Function with the same name is untouched.
+	assert.Contains(t, src, "fmt.Println(greet(name))")
+	assert.Contains(t, src, "func greet(name string) string {")
+}
+
+func TestRenameObject_ShadowedIdentifier(t *testing.T) {
+	pkg := loadRenamePkg(t)
+
+	err := RenameObject(pkg, findFunc(pkg, "greet", false), "salute")
+	assert.Nil(t, err, err)
+
+	src := pkgSource(t, pkg)
+	assert.Contains(t, src, "func salute(name string) string {")
+	assert.Contains(t, src, "fmt.Println(salute(name))")
+	assert.Contains(t, src, "log.Print(g.greet(name))")
+	assert.Contains(t, src, "greet := \"Hello\"")
+	assert.Contains(t, src, "fmt.Println(greet)\n")
+}
+
+func TestRenameObject_SubPackage(t *testing.T) {
+	pkg := loadRenamePkg(t)
+	subPkg := pkg.SubPkgs()[0]
+
+	err := RenameObject(pkg, findFunc(subPkg, "Print", false), "Println")
+	assert.Nil(t, err, err)
+
+	assert.Contains(t, pkgSource(t, subPkg), "func Println(msg string) {")
+	assert.Contains(t, pkgSource(t, pkg), "log.Println(g.greet(name))")
+}
+
+func TestRenameObject_Conflict(t *testing.T) {
+	pkg := loadRenamePkg(t)
+
+	err := RenameObject(pkg, findFunc(pkg, "greet", false), "Greet")
+	assert.NotNil(t, err)
+
+	// Unexported name used from another package.
+	err = RenameObject(pkg, findFunc(pkg.SubPkgs()[0], "Print", false), "print")
+	assert.NotNil(t, err)
+
+	// Local variable shadowing the new name.
+	err = RenameObject(pkg, findFunc(pkg, "greet", false), "name")
+	assert.NotNil(t, err)
+
+	assert.Contains(t, pkgSource(t, pkg), "func greet(name string) string {")
+}
+
+func TestRenameFuncs(t *testing.T) {
+	pkg := loadRenamePkg(t)
+
+	err := RenameFuncs(pkg, func(fn *types.Func) (string, bool) {
+		if fn.Name() != "Print" {
+			return "", false
+		}
+
+		return strings.ToUpper(fn.Name()), true
+	})
+	assert.Nil(t, err, err)
+
+	assert.Contains(t, pkgSource(t, pkg.SubPkgs()[0]), "func PRINT(msg string) {")
+	assert.Contains(t, pkgSource(t, pkg), "log.PRINT(g.greet(name))")
+}
+
+func TestRenameFuncs_TestFiles(t *testing.T) {
+	pkg, err := parse.PackageFromOverlay(filepath.Join("_data", "rename_tests"), map[string][]byte{
+		"greet.go": []byte(`package greet
+
+func Greet(name string) string {
+	return "Hello " + name
+}
+`),
+		"greet_test.go": []byte(`package greet
+
+import "testing"
+
+func TestGreet(t *testing.T) {
+	if Greet("World") != "Hello World" {
+		t.Fail()
+	}
+}
+`),
+		"example_test.go": []byte(`package greet_test
+
+import (
+	"fmt"
+
+	"github.com/negrel/asttk/pkg/utils/_data/rename_tests"
+)
+
+func ExampleGreet() {
+	fmt.Println(greet.Greet("World"))
+}
+`),
+	}, false, parse.WithTests(), parse.WithXTest())
+	assert.Nil(t, err, err)
+	assert.Len(t, pkg.TestFiles, 1)
+	assert.NotNil(t, pkg.XTest())
+
+	err = RenameFuncs(pkg, func(fn *types.Func) (string, bool) {
+		return "Hello", fn.Name() == "Greet"
+	})
+	assert.Nil(t, err, err)
+
+	assert.Contains(t, pkgSource(t, pkg), "func Hello(name string) string {")
+
+	testSrc, err := pkg.TestFiles[0].Bytes()
+	assert.Nil(t, err, err)
+	assert.Contains(t, string(testSrc), `if Hello("World") != "Hello World" {`)
+
+	assert.Contains(t, pkgSource(t, pkg.XTest()), `fmt.Println(greet.Hello("World"))`)
+}
+
+func loadOverlayPkg(t *testing.T, src string) *parse.GoPackage {
+	pkg, err := parse.PackageFromOverlay(filepath.Join("_data", "rename_overlay"), map[string][]byte{
+		"overlay.go": []byte(src),
+	}, false)
+	assert.Nil(t, err, err)
+
+	return pkg
+}
+
+func TestRenameFuncs_Conflict(t *testing.T) {
+	src := `package overlay
+
+func a() {}
+
+func b() {}
+
+func main() {
+	a()
+	b()
+}
+`
+	pkg := loadOverlayPkg(t, src)
+
+	// Both functions renamed to the same name.
+	err := RenameFuncs(pkg, func(fn *types.Func) (string, bool) {
+		return "c", fn.Name() == "a" || fn.Name() == "b"
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, src, pkgSource(t, pkg))
+
+	// The second rename fail, the first one isn't applied.
+	err = RenameFuncs(pkg, func(fn *types.Func) (string, bool) {
+		switch fn.Name() {
+		case "a":
+			return "d", true
+		case "b":
+			return "main", true
+		}
+		return "", false
+	})
+	assert.NotNil(t, err)
+	assert.Equal(t, src, pkgSource(t, pkg))
+
+	// Swapping names doesn't conflict.
+	err = RenameFuncs(pkg, func(fn *types.Func) (string, bool) {
+		switch fn.Name() {
+		case "a":
+			return "b", true
+		case "b":
+			return "a", true
+		}
+		return "", false
+	})
+	assert.Nil(t, err, err)
+	assert.Contains(t, pkgSource(t, pkg), "func b() {}\n\nfunc a() {}\n\nfunc main() {\n\tb()\n\ta()\n}")
+}
+
+func TestRenameObject_Interface(t *testing.T) {
+	pkg := loadOverlayPkg(t, `package overlay
+
+import "fmt"
+
+type named struct{}
+
+func (n named) String() string { return "named" }
+
+func (n named) Name() string { return "named" }
+
+type wrapper struct {
+	named
+}
+
+type greeter interface {
+	Greet() string
+}
+
+type hello struct{}
+
+func (h *hello) Greet() string { return "hello" }
+
+func main() {
+	var g greeter = &hello{}
+	var s fmt.Stringer = wrapper{}
+	fmt.Println(s, g.Greet())
+}
+`)
+
+	// wrapper implement fmt.Stringer through the embedded named type.
+	err := RenameObject(pkg, findFunc(pkg, "String", true), "Str")
+	assert.NotNil(t, err)
+
+	// *hello is used as a greeter.
+	err = RenameObject(pkg, findFunc(pkg, "Greet", true), "Salute")
+	assert.NotNil(t, err)
+
+	err = RenameObject(pkg, findFunc(pkg, "Name", true), "Label")
+	assert.Nil(t, err, err)
+	assert.Contains(t, pkgSource(t, pkg), "func (n named) Label() string {")
+}
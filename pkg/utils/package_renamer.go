@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/negrel/asttk/pkg/inspector"
+	"github.com/negrel/asttk/pkg/parse"
+)
+
+// PackageRename describe the changes applied by RenamePackage.
+type PackageRename struct {
+	// Name is the new name of the package, the name is kept if empty.
+	Name string
+	// Dir is the new directory of the package, the package isn't moved if
+	// empty. The directory is moved on disk with its sub-packages and must
+	// stay in the same module, see parse.GoPackage.Move.
+	Dir string
+	// Importers are the packages, with their sub-packages, whose imports of
+	// the renamed package are updated.
+	Importers []*parse.GoPackage
+}
+
+// RenamePackage change the name of every file of the given package and move
+// it according to the given PackageRename. The imports of the package, and of
+// its sub-packages if it is moved, are updated in the importers and in the
+// package itself, with its sub-packages and its external test package, if it
+// was loaded. Qualified
+// identifiers referring to the package are renamed unless the import is named
+// or the new name conflicts with another identifier of the importing file, in
+// which case the import is named after the old package name.
+func RenamePackage(pkg *parse.GoPackage, rename PackageRename) error {
+	if rename.Name != "" && !token.IsIdentifier(rename.Name) {
+		return fmt.Errorf("%v is an invalid package name", rename.Name)
+	}
+
+	oldPkgPath := pkg.PkgPath()
+	if rename.Dir != "" {
+		err := pkg.Move(rename.Dir)
+		if err != nil {
+			return err
+		}
+	}
+	newPkgPath := pkg.PkgPath()
+
+	oldName := ""
+	if len(pkg.Files) > 0 {
+		oldName = pkg.Files[0].AST().Name.Name
+	}
+	if rename.Name != "" {
+		for _, file := range pkg.AllFiles() {
+			inspector.New(ChangePackage(rename.Name)).Inspect(file.AST())
+		}
+
+		if xtest := pkg.XTest(); xtest != nil {
+			for _, file := range xtest.Files {
+				inspector.New(ChangePackage(rename.Name + "_test")).Inspect(file.AST())
+			}
+		}
+	}
+
+	// The package, its external test package and its sub-packages are always
+	// updated, they may import the package or its sub-packages.
+	pkgs := []*parse.GoPackage{}
+	updated := map[*parse.GoPackage]bool{}
+	for _, importer := range append([]*parse.GoPackage{pkg}, rename.Importers...) {
+		for _, p := range allPkgs(importer) {
+			if !updated[p] {
+				updated[p] = true
+				pkgs = append(pkgs, p)
+			}
+		}
+	}
+
+	for _, p := range pkgs {
+		for _, file := range p.AllFiles() {
+			for _, spec := range file.AST().Imports {
+				path := importPath(spec)
+
+				if path == oldPkgPath && rename.Name != "" && rename.Name != oldName {
+					renameImport(file, spec, oldName, rename.Name)
+				}
+
+				if path == oldPkgPath || strings.HasPrefix(path, oldPkgPath+"/") {
+					spec.Path.Value = strconv.Quote(newPkgPath + strings.TrimPrefix(path, oldPkgPath))
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// renameImport rename the qualified identifiers referring to the given unnamed
+// import. If the new name conflicts, the import is named after the old name.
+func renameImport(file *parse.GoFile, spec *ast.ImportSpec, oldName, newName string) {
+	if spec.Name != nil {
+		return
+	}
+
+	im := NewImportManager(file)
+	if im.conflict(newName) {
+		spec.Name = &ast.Ident{NamePos: spec.Path.Pos(), Name: oldName}
+		return
+	}
+
+	inspector.New(im.renameReferences(im.pkgName(spec), oldName, newName)).Inspect(file.AST())
+}
@@ -0,0 +1,173 @@
+package utils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/negrel/asttk/pkg/inspector"
+	"github.com/negrel/asttk/pkg/parse"
+)
+
+const renamePkgPath = "github.com/negrel/asttk/pkg/utils/_data/renamepkg"
+
+var renamePkgFiles = map[string][]byte{
+	"app.go": []byte(`package app
+
+import (
+	"github.com/negrel/asttk/pkg/utils/_data/renamepkg/greet"
+	"github.com/negrel/asttk/pkg/utils/_data/renamepkg/greet/log"
+)
+
+func Run() {
+	log.Print(greet.Hello("World"))
+}
+`),
+	"conflict.go": []byte(`package app
+
+import "github.com/negrel/asttk/pkg/utils/_data/renamepkg/greet"
+
+func hello(salute string) string {
+	return greet.Hello(salute)
+}
+`),
+	filepath.Join("greet", "greet.go"): []byte(`package greet
+
+func Hello(name string) string {
+	return "Hello " + name
+}
+`),
+	filepath.Join("greet", "log", "log.go"): []byte(`package log
+
+func Print(msg string) {
+	println(msg)
+}
+`),
+}
+
+func TestChangePackage(t *testing.T) {
+	file, err := parse.FileFromSource(filepath.Join("_data", "renamepkg", "app.go"), renamePkgFiles["app.go"])
+	assert.Nil(t, err, err)
+
+	inspector.New(ChangePackage("main")).Inspect(file.AST())
+	assert.Equal(t, "main", file.AST().Name.Name)
+}
+
+func TestRenamePackage(t *testing.T) {
+	root, err := parse.PackageFromOverlay(filepath.Join("_data", "renamepkg"), renamePkgFiles, true)
+	assert.Nil(t, err, err)
+	pkg := root.SubPkgs()[0]
+
+	err = RenamePackage(pkg, PackageRename{Name: "-"})
+	assert.NotNil(t, err)
+
+	err = RenamePackage(pkg, PackageRename{
+		Name:      "salute",
+		Importers: []*parse.GoPackage{root},
+	})
+	assert.Nil(t, err, err)
+
+	assert.Equal(t, `package salute
+
+func Hello(name string) string {
+	return "Hello " + name
+}
+`, pkgSource(t, pkg))
+
+	assert.Equal(t, `package app
+
+import (
+	"github.com/negrel/asttk/pkg/utils/_data/renamepkg/greet"
+	"github.com/negrel/asttk/pkg/utils/_data/renamepkg/greet/log"
+)
+
+func Run() {
+	log.Print(salute.Hello("World"))
+}
+package app
+
+import greet "github.com/negrel/asttk/pkg/utils/_data/renamepkg/greet"
+
+func hello(salute string) string {
+	return greet.Hello(salute)
+}
+`, pkgSource(t, root))
+}
+
+func TestRenamePackage_Move(t *testing.T) {
+	// The packages must be part of the module to be loaded.
+	tmp, err := ioutil.TempDir("_data", "renamepkg")
+	assert.Nil(t, err, err)
+	defer os.RemoveAll(tmp)
+
+	appPkgPath := "github.com/negrel/asttk/pkg/utils/" + filepath.ToSlash(tmp) + "/app"
+	for path, src := range renamePkgFiles {
+		path = filepath.Join(tmp, "app", path)
+		src = bytes.ReplaceAll(src, []byte(renamePkgPath), []byte(appPkgPath))
+
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, ioutil.WriteFile(path, src, 0644))
+	}
+
+	root, err := parse.Package(filepath.Join(tmp, "app"), true)
+	assert.Nil(t, err, err)
+	pkg := root.SubPkgs()[0]
+
+	err = RenamePackage(pkg, PackageRename{
+		Dir:       filepath.Join(tmp, "app", "hello"),
+		Importers: []*parse.GoPackage{root},
+	})
+	assert.Nil(t, err, err)
+
+	_, err = os.Stat(filepath.Join(tmp, "app", "hello", "log", "log.go"))
+	assert.Nil(t, err, err)
+
+	pkgPath := appPkgPath + "/hello"
+	assert.Equal(t, pkgPath, pkg.PkgPath())
+	assert.Equal(t, pkgPath+"/log", pkg.SubPkgs()[0].PkgPath())
+	assert.Equal(t, pkgPath, importPath(root.Files[0].AST().Imports[0]))
+	assert.Equal(t, pkgPath+"/log", importPath(root.Files[0].AST().Imports[1]))
+
+	src, err := root.Files[0].Bytes()
+	assert.Nil(t, err, err)
+	assert.Contains(t, string(src), "log.Print(greet.Hello(\"World\"))")
+}
+
+func TestRenamePackage_MoveWithoutImporters(t *testing.T) {
+	// The packages must be part of the module to be loaded.
+	tmp, err := ioutil.TempDir("_data", "renamepkg")
+	assert.Nil(t, err, err)
+	defer os.RemoveAll(tmp)
+
+	greetPkgPath := "github.com/negrel/asttk/pkg/utils/" + filepath.ToSlash(tmp) + "/greet"
+	files := map[string]string{
+		"greet.go": `package greet
+
+import "` + greetPkgPath + `/log"
+
+func Hello(name string) {
+	log.Print("Hello " + name)
+}
+`,
+		filepath.Join("log", "log.go"): string(renamePkgFiles[filepath.Join("greet", "log", "log.go")]),
+	}
+	for path, src := range files {
+		path = filepath.Join(tmp, "greet", path)
+
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, ioutil.WriteFile(path, []byte(src), 0644))
+	}
+
+	pkg, err := parse.Package(filepath.Join(tmp, "greet"), true)
+	assert.Nil(t, err, err)
+
+	// The imports of the sub-packages are updated in the package itself.
+	err = RenamePackage(pkg, PackageRename{Dir: filepath.Join(tmp, "hello")})
+	assert.Nil(t, err, err)
+	assert.Equal(t, path.Dir(greetPkgPath)+"/hello/log", importPath(pkg.Files[0].AST().Imports[0]))
+}
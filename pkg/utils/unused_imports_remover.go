@@ -3,23 +3,32 @@ package utils
 import (
 	"go/ast"
 	"go/token"
-	"strings"
+	"go/types"
+	"strconv"
 
 	"github.com/negrel/asttk/pkg/inspector"
 )
 
 type unusedImportsRemover struct {
-	pkgName         *ast.Ident
-	allImports      []*ast.ImportSpec
-	requiredImports map[string]ast.Spec
+	fset *token.FileSet
+	info *types.Info
+	used map[*types.PkgName]bool
 }
 
 // RemoveUnusedImports method return an inspector.Inspector and remover function.
-// Removing unused imports is a two step process. First, the inspector will scan
-// the required package of the given ast.File. Then returned function will remove
-// the unused imports.
-func RemoveUnusedImports() (inspector.Inspector, func(file *ast.File)) {
-	uir := new(unusedImportsRemover)
+// Removing unused imports is a two step process. First, the inspector will collect
+// the imported packages used by the inspected ast.File according to the given type
+// information. Then returned function will remove the unused imports.
+//
+// Blank, dot and cgo imports are always kept, as well as every import if info
+// is nil. The order of the remaining imports,
+// their grouping and their comments are preserved.
+func RemoveUnusedImports(fset *token.FileSet, info *types.Info) (inspector.Inspector, func(file *ast.File)) {
+	uir := &unusedImportsRemover{
+		fset: fset,
+		info: info,
+		used: make(map[*types.PkgName]bool),
+	}
 
 	return uir.inspect, uir.removeImports
 }
@@ -27,12 +36,6 @@ func RemoveUnusedImports() (inspector.Inspector, func(file *ast.File)) {
 func (uir *unusedImportsRemover) inspect(node ast.Node) (recursive bool) {
 	recursive = true
 
-	if file, isFile := node.(*ast.File); isFile {
-		uir.pkgName = file.Name
-		uir.allImports = file.Imports
-		uir.requiredImports = make(map[string]ast.Spec)
-	}
-
 	if decl, isGenDecl := node.(*ast.GenDecl); isGenDecl {
 		if decl.Tok == token.IMPORT {
 			return false
@@ -40,46 +43,193 @@ func (uir *unusedImportsRemover) inspect(node ast.Node) (recursive bool) {
 	}
 
 	ident, ok := node.(*ast.Ident)
-	if !ok || ident.Obj != nil || ident == uir.pkgName {
+	if !ok || uir.info == nil {
 		return
 	}
 
-	for _, _import := range uir.allImports {
-		// Storing package identifier (name or last folder name in path)
-		var name string
-		if identifier := _import.Name; identifier != nil {
-			name = identifier.Name
-		} else {
-			slice := strings.Split(_import.Path.Value, "/")
-			name = slice[len(slice)-1]
-			name = strings.Trim(name, "\"")
-		}
-
-		if name == ident.Name {
-			uir.requiredImports[name] = _import
-		}
+	if pkgName, isPkgName := uir.info.Uses[ident].(*types.PkgName); isPkgName {
+		uir.used[pkgName] = true
 	}
 
 	return
 }
 
+func (uir *unusedImportsRemover) isUsed(spec *ast.ImportSpec) bool {
+	if spec.Name != nil && (spec.Name.Name == "_" || spec.Name.Name == ".") {
+		return true
+	}
+
+	if importPath(spec) == "C" {
+		return true
+	}
+
+	// Without type information, the import is kept.
+	if uir.info == nil {
+		return true
+	}
+
+	var obj types.Object
+	if spec.Name != nil {
+		obj = uir.info.Defs[spec.Name]
+	} else {
+		obj = uir.info.Implicits[spec]
+	}
+
+	pkgName, isPkgName := obj.(*types.PkgName)
+	if !isPkgName {
+		return true
+	}
+
+	return uir.used[pkgName]
+}
+
 func (uir *unusedImportsRemover) removeImports(file *ast.File) {
+	removedComments := make(map[*ast.CommentGroup]bool)
+
+	decls := file.Decls[:0]
 	for _, d := range file.Decls {
 		decl, ok := d.(*ast.GenDecl)
-		if !ok {
+		if !ok || decl.Tok != token.IMPORT {
+			decls = append(decls, d)
 			continue
 		}
 
-		if decl.Tok != token.IMPORT {
+		uir.removeSpecs(decl, removedComments)
+
+		if len(decl.Specs) == 0 {
+			if decl.Doc != nil {
+				removedComments[decl.Doc] = true
+			}
 			continue
 		}
 
-		decls := make([]ast.Spec, 0, len(uir.requiredImports))
-		for _, _import := range uir.requiredImports {
-			decls = append(decls, _import)
+		decls = append(decls, decl)
+	}
+	file.Decls = decls
+
+	imports := file.Imports[:0]
+	for _, spec := range file.Imports {
+		if uir.isUsed(spec) {
+			imports = append(imports, spec)
 		}
-		decl.Specs = decls
+	}
+	file.Imports = imports
 
-		break
+	comments := file.Comments[:0]
+	for _, comment := range file.Comments {
+		if !removedComments[comment] {
+			comments = append(comments, comment)
+		}
 	}
+	file.Comments = comments
+}
+
+// removeSpecs remove the unused imports of the given declaration. The kept
+// imports are moved up to close the hole left by the removed ones, unless they
+// are preceded by a blank line, so the import groups remain the same. The line
+// table of the file isn't modified.
+func (uir *unusedImportsRemover) removeSpecs(decl *ast.GenDecl, removedComments map[*ast.CommentGroup]bool) {
+	tokFile := uir.fset.File(decl.Pos())
+	// Last line of the previous spec, or of the opening parenthesis.
+	previousLine := uir.line(decl.Lparen)
+	// Last line of the previous kept spec, once moved.
+	keptLine := previousLine
+	blankLine := false
+
+	specs := decl.Specs[:0]
+	for _, s := range decl.Specs {
+		spec := s.(*ast.ImportSpec)
+
+		start, end := uir.line(specStart(spec)), uir.line(specEnd(spec))
+		blankLine = blankLine || start-previousLine > 1
+		previousLine = end
+
+		if !uir.isUsed(spec) {
+			if spec.Doc != nil {
+				removedComments[spec.Doc] = true
+			}
+			if spec.Comment != nil {
+				removedComments[spec.Comment] = true
+			}
+			continue
+		}
+
+		line := keptLine + 1
+		if blankLine {
+			line++
+		}
+		blankLine = false
+
+		if tokFile != nil && decl.Lparen.IsValid() && line < start {
+			moveSpec(tokFile, spec, line-start)
+		}
+		keptLine = uir.line(specEnd(spec))
+
+		specs = append(specs, spec)
+	}
+
+	decl.Specs = specs
+}
+
+// moveSpec move the given import spec and its comments by the given number of
+// lines. The import and its line comments are moved to the start of the line.
+func moveSpec(tokFile *token.File, spec *ast.ImportSpec, lines int) {
+	moveLine := func(pos token.Pos) token.Pos {
+		return tokFile.LineStart(tokFile.Line(pos) + lines)
+	}
+
+	if spec.Doc != nil {
+		for _, comment := range spec.Doc.List {
+			comment.Slash = moveLine(comment.Slash)
+		}
+	}
+
+	pos := moveLine(spec.Pos())
+	if spec.Name != nil {
+		spec.Name.NamePos = pos
+	}
+	spec.Path.ValuePos = pos
+	// The path doesn't fit in the line start, the spec end is overridden so
+	// it doesn't span several lines.
+	spec.EndPos = pos
+
+	// Comments at the position of the spec are printed after it.
+	if spec.Comment != nil {
+		for _, comment := range spec.Comment.List {
+			comment.Slash = pos
+		}
+	}
+}
+
+func (uir *unusedImportsRemover) line(pos token.Pos) int {
+	if !pos.IsValid() {
+		return 0
+	}
+
+	return uir.fset.Position(pos).Line
+}
+
+func specStart(spec *ast.ImportSpec) token.Pos {
+	if spec.Doc != nil {
+		return spec.Doc.Pos()
+	}
+
+	return spec.Pos()
+}
+
+func specEnd(spec *ast.ImportSpec) token.Pos {
+	if spec.Comment != nil {
+		return spec.Comment.End()
+	}
+
+	return spec.End()
+}
+
+func importPath(spec *ast.ImportSpec) string {
+	path, err := strconv.Unquote(spec.Path.Value)
+	if err != nil {
+		return ""
+	}
+
+	return path
 }
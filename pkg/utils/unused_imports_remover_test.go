@@ -1,16 +1,16 @@
 package utils
 
 import (
-	"bytes"
+	"fmt"
 	"go/ast"
 	"go/format"
-	"go/parser"
-	"go/token"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/negrel/asttk/pkg/inspector"
+	"github.com/negrel/asttk/pkg/parse"
 )
 
 type testCase struct {
@@ -156,37 +156,220 @@ func main() {
 	fmt.Println(" world")
 }
 `},
+	// package name differ from the last element of the import path.
+	{
+		src: `package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/negrel/asttk/pkg/utils/_data/imports/go-greet"
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	out, _ := yaml.Marshal(greet.Hello("World"))
+	fmt.Println(string(out))
 }
+`,
+		out: `package main
 
-func TestUnusedImportsRemover(t *testing.T) {
-	fset := token.NewFileSet()
+import (
+	"fmt"
 
-	findUnusedImports, removeUnusedImports := RemoveUnusedImports()
-	editor := inspector.New(findUnusedImports)
+	"github.com/negrel/asttk/pkg/utils/_data/imports/go-greet"
+	"gopkg.in/yaml.v3"
+)
 
-	for _, test := range unusedImportsRemoverTests {
-		file, err := parser.ParseFile(fset, "", test.src, parser.AllErrors)
-		assert.Nil(t, err)
-		expectedFile, err := parser.ParseFile(fset, "", test.out, parser.AllErrors)
-		assert.Nil(t, err)
+func main() {
+	out, _ := yaml.Marshal(greet.Hello("World"))
+	fmt.Println(string(out))
+}
+`},
+	// blank and dot imports are kept.
+	{
+		src: `package main
 
-		editor.Inspect(file)
-		removeUnusedImports(file)
+import (
+	_ "embed"
+	"os"
+	. "strings"
+)
+
+func main() {
+	println(ToUpper("Hello world"))
+}
+`,
+		out: `package main
+
+import (
+	_ "embed"
+	. "strings"
+)
+
+func main() {
+	println(ToUpper("Hello world"))
+}
+`},
+	// order, groups and comments are preserved.
+	{
+		src: `package main
+
+import (
+	// fmt print things.
+	"fmt" // fmt comment
+	// os is unused.
+	"os" // os comment
+	"strings"
+
+	"bytes"
+
+	// log is used.
+	"log"
+	"errors"
+)
+
+func main() {
+	log.Println(fmt.Sprint(strings.ToUpper("Hello world")))
+}
+`,
+		out: `package main
+
+import (
+	// fmt print things.
+	"fmt" // fmt comment
+	"strings"
+
+	// log is used.
+	"log"
+)
 
-		actualResult, err := getBytes(file)
+func main() {
+	log.Println(fmt.Sprint(strings.ToUpper("Hello world")))
+}
+`},
+	// several import declarations.
+	{
+		src: `package main
+
+import "fmt"
+
+// os is unused.
+import "os"
+
+import (
+	"log"
+	"strings"
+)
+
+func main() {
+	log.Println(fmt.Sprint("Hello world"))
+}
+`,
+		out: `package main
+
+import "fmt"
+
+import (
+	"log"
+)
+
+func main() {
+	log.Println(fmt.Sprint("Hello world"))
+}
+`},
+}
+
+func TestUnusedImportsRemover(t *testing.T) {
+	for i, test := range unusedImportsRemoverTests {
+		filePath := filepath.Join("_data", "imports", fmt.Sprintf("case_%v", i), "main.go")
+		file, err := parse.FileFromSource(filePath, []byte(test.src))
+		assert.Nil(t, err, err)
+
+		findUnusedImports, removeUnusedImports := RemoveUnusedImports(
+			file.FileSet(),
+			file.Package().TypesInfo(),
+		)
+		editor := inspector.New(findUnusedImports)
+
+		editor.Inspect(file.AST())
+		removeUnusedImports(file.AST())
+
+		actualResult, err := file.Bytes()
 		assert.Nil(t, err)
 
-		expectedResult, err := getBytes(expectedFile)
+		expectedResult, err := format.Source([]byte(test.out))
 		assert.Nil(t, err)
 
-		assert.EqualValues(t, string(expectedResult), string(actualResult))
+		assert.EqualValues(t, string(expectedResult), string(actualResult), "test case %v", i)
 	}
 }
 
-func getBytes(file *ast.File) ([]byte, error) {
-	buf := &bytes.Buffer{}
+func TestUnusedImportsRemover_Position(t *testing.T) {
+	filePath := filepath.Join("_data", "imports", "position", "main.go")
+	file, err := parse.FileFromSource(filePath, []byte(`package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+func main() {
+	fmt.Println("Hello world")
+}
+`))
+	assert.Nil(t, err, err)
+
+	mainDecl := file.AST().Decls[1]
+	assert.Equal(t, 9, file.Position(mainDecl).Line)
+
+	findUnusedImports, removeUnusedImports := RemoveUnusedImports(file.FileSet(), file.Package().TypesInfo())
+	inspector.New(findUnusedImports).Inspect(file.AST())
+	removeUnusedImports(file.AST())
+
+	// The positions still match the source of the file.
+	assert.Equal(t, 9, file.Position(mainDecl).Line)
+	src, err := file.Source(mainDecl.(*ast.FuncDecl).Name)
+	assert.Nil(t, err, err)
+	assert.Equal(t, "main", string(src))
+
+	assertSource(t, `package main
+
+import (
+	"fmt"
+)
+
+func main() {
+	fmt.Println("Hello world")
+}
+`, file)
+}
+
+func TestUnusedImportsRemover_NoTypesInfo(t *testing.T) {
+	src := `package main
 
-	err := format.Node(buf, token.NewFileSet(), file)
+import (
+	"fmt"
+	"os"
+)
 
-	return buf.Bytes(), err
+func main() {
+	fmt.Println("Hello world")
+}
+`
+	filePath := filepath.Join("_data", "imports", "syntax_only", "main.go")
+	file, err := parse.FileFromSource(filePath, []byte(src), parse.WithSyntaxOnly())
+	assert.Nil(t, err, err)
+	assert.Nil(t, file.Package().TypesInfo())
+
+	findUnusedImports, removeUnusedImports := RemoveUnusedImports(file.FileSet(), file.Package().TypesInfo())
+	inspector.New(findUnusedImports).Inspect(file.AST())
+	removeUnusedImports(file.AST())
+
+	// Without type information, every import is kept.
+	actualResult, err := file.Bytes()
+	assert.Nil(t, err)
+	assert.Equal(t, src, string(actualResult))
 }